@@ -1,6 +1,18 @@
 package analyze
 
-import "embed"
+import (
+	"embed"
+	"io/fs"
+)
 
 //go:embed prompts/*.txt
 var embeddedPrompts embed.FS
+
+// BuiltinPrompts returns the builtin prompt templates, such as "_base.txt" and "summary.txt".
+func BuiltinPrompts() fs.FS {
+	prompts, err := fs.Sub(embeddedPrompts, "prompts")
+	if err != nil {
+		panic(err)
+	}
+	return prompts
+}
@@ -0,0 +1,107 @@
+package analyze
+
+import (
+	"cmp"
+	"go/token"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/loov/dreamlint/extract"
+)
+
+// Example is a reviewed finding, shown to the LLM as a few-shot example of
+// what is and what is not an issue.
+type Example struct {
+	Unit     string // ID of the unit the finding was reported in
+	Category string // analysis pass that reported the finding
+	Code     string
+	Message  string
+	Valid    bool // confirmed issue rather than a false positive
+}
+
+// SetExamples sets the reviewed findings to take few-shot examples from.
+// The prompt of each analysis pass includes up to the configured number of
+// examples of the pass, preferring those most similar to the analyzed unit.
+func (p *Pipeline) SetExamples(examples []Example) {
+	p.examples = examples
+}
+
+// passExamples returns the few-shot examples for pass when analyzing unit.
+func (p *Pipeline) passExamples(pass string, unit *extract.AnalysisUnit) []ExampleContext {
+	limit := p.config.Examples.Max
+	if limit <= 0 || len(p.examples) == 0 {
+		return nil
+	}
+
+	type scored struct {
+		example Example
+		score   float64
+	}
+	var unitIdents map[string]bool
+	var candidates []scored
+	for _, example := range p.examples {
+		if example.Category != pass {
+			continue
+		}
+		if unitIdents == nil {
+			var bodies []string
+			for _, fn := range unit.Functions {
+				bodies = append(bodies, fn.Body)
+			}
+			unitIdents = identifiers(strings.Join(bodies, "\n"))
+		}
+		candidates = append(candidates, scored{example, exampleSimilarity(example, unit, unitIdents)})
+	}
+	slices.SortStableFunc(candidates, func(a, b scored) int {
+		return cmp.Compare(b.score, a.score)
+	})
+
+	var examples []ExampleContext
+	for _, candidate := range candidates[:min(limit, len(candidates))] {
+		examples = append(examples, ExampleContext{
+			Code:    candidate.example.Code,
+			Message: candidate.example.Message,
+			Valid:   candidate.example.Valid,
+		})
+	}
+	return examples
+}
+
+// exampleSimilarity scores how similar example is to unit, by the share of
+// the identifiers of its code used in the unit, preferring examples from
+// the same unit and package.
+func exampleSimilarity(example Example, unit *extract.AnalysisUnit, unitIdents map[string]bool) float64 {
+	var score float64
+	idents := identifiers(example.Code)
+	if len(idents) > 0 {
+		shared := 0
+		for ident := range idents {
+			if unitIdents[ident] {
+				shared++
+			}
+		}
+		score = float64(shared) / float64(len(idents))
+	}
+
+	switch {
+	case example.Unit == unit.ID:
+		score += 1
+	case len(unit.Functions) > 0 && strings.HasPrefix(example.Unit, unit.Functions[0].Package+"."):
+		score += 0.5
+	}
+	return score
+}
+
+var identifierRx = regexp.MustCompile(`[\pL_][\pL\pN_]*`)
+
+// identifiers returns the set of identifiers in code, leaving out keywords.
+func identifiers(code string) map[string]bool {
+	idents := make(map[string]bool)
+	for _, ident := range identifierRx.FindAllString(code, -1) {
+		if !token.IsKeyword(ident) {
+			idents[ident] = true
+		}
+	}
+	return idents
+}
@@ -0,0 +1,153 @@
+package analyze
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+
+	"github.com/rogpeppe/go-internal/diff"
+
+	"github.com/loov/dreamlint/extract"
+	"github.com/loov/dreamlint/report"
+)
+
+// shouldFix reports whether a fix should be generated for issue.
+func (p *Pipeline) shouldFix(issue report.Issue) bool {
+	fix := p.config.Fix
+	if !fix.Enabled {
+		return false
+	}
+	if fix.MinSeverity == "" {
+		return true
+	}
+	return p.config.IssueSeverities().AtLeast(issue.Severity, report.Severity(fix.MinSeverity))
+}
+
+// Fix generates a fix of an issue reported earlier for unit. The issue must
+// be in one of the functions of unit and its file must not have changed.
+// The fix prompt is loaded only when fixes are enabled in the config.
+func (p *Pipeline) Fix(ctx context.Context, unit *extract.AnalysisUnit, calleeSummaries map[string]*SummaryResponse, issue report.Issue) (*report.Fix, error) {
+	var fn *extract.FunctionInfo
+	for _, f := range unit.Functions {
+		if f.ID() == issue.Function {
+			fn = f
+		}
+	}
+	if fn == nil {
+		return nil, fmt.Errorf("function %s not found in %s", issue.Function, unit.ID)
+	}
+
+	promptCtx := p.BuildPromptContext(unit, calleeSummaries)
+	if summary, ok := calleeSummaries[unit.ID]; ok {
+		promptCtx.Summary = summaryContext(summary)
+	}
+	return p.generateFix(ctx, promptCtx, fn, issue)
+}
+
+// generateFix asks the LLM to rewrite fn so that issue is fixed. The fix is
+// returned only when the changed file parses and passes the fix checker.
+func (p *Pipeline) generateFix(ctx context.Context, promptCtx PromptContext, fn *extract.FunctionInfo, issue report.Issue) (*report.Fix, error) {
+	filename := fn.Position.Filename
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	start := fn.Position.Offset
+	end := start + len(fn.Body)
+	if start < 0 || end > len(content) || string(content[start:end]) != fn.Body {
+		return nil, fmt.Errorf("%s changed since it was loaded", filename)
+	}
+
+	tmpl, ok := p.prompts[p.config.Fix.Prompt]
+	if !ok {
+		return nil, errors.New("fix prompt not loaded")
+	}
+
+	// Only the function containing the issue is rewritten
+	promptCtx.Functions = nil
+	promptCtx.Name = fn.Name
+	promptCtx.Package = fn.Package
+	promptCtx.Receiver = fn.Receiver
+	promptCtx.Signature = fn.Signature
+	promptCtx.Body = fn.Body
+	promptCtx.Godoc = fn.Godoc
+	promptCtx.Issue = &IssueContext{
+		Severity:   string(issue.Severity),
+		Code:       issue.Snippet,
+		Message:    issue.Message,
+		Suggestion: issue.Suggestion,
+	}
+	prompt, err := ExecutePrompt(tmpl, promptCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	var fix *report.Fix
+	_, err = p.complete(ctx, p.config.Fix.Pass(), prompt, FixSchema, func(response string) error {
+		resp, err := ParseFixResponse(response)
+		if err != nil {
+			return err
+		}
+		fix, err = p.makeFix(fn, content, resp.Code)
+		return err
+	})
+	return fix, err
+}
+
+// makeFix replaces fn in content with code and returns the change.
+func (p *Pipeline) makeFix(fn *extract.FunctionInfo, content []byte, code string) (*report.Fix, error) {
+	replacement := strings.TrimSpace(trimCodeFence(code))
+	if replacement == "" {
+		return nil, errors.New("fix is empty")
+	}
+	if replacement == fn.Body {
+		return nil, errors.New("fix does not change the function")
+	}
+
+	filename := fn.Position.Filename
+	start := fn.Position.Offset
+	end := start + len(fn.Body)
+	src := make([]byte, 0, len(content)-len(fn.Body)+len(replacement))
+	src = append(src, content[:start]...)
+	src = append(src, replacement...)
+	src = append(src, content[end:]...)
+
+	if _, err := parser.ParseFile(token.NewFileSet(), filename, src, parser.SkipObjectResolution); err != nil {
+		return nil, fmt.Errorf("fix does not parse: %w", err)
+	}
+	if p.checkFix != nil {
+		if err := p.checkFix(filename, src); err != nil {
+			return nil, fmt.Errorf("fix does not type-check: %w", err)
+		}
+	}
+
+	endColumn := fn.Position.Column + len(fn.Body)
+	if i := strings.LastIndex(fn.Body, "\n"); i >= 0 {
+		endColumn = len(fn.Body) - i
+	}
+	return &report.Fix{
+		Diff:        string(diff.Diff(filename, content, filename, src)),
+		StartLine:   fn.Position.Line,
+		StartColumn: fn.Position.Column,
+		EndLine:     fn.EndLine(),
+		EndColumn:   endColumn,
+		Replacement: replacement,
+	}, nil
+}
+
+// trimCodeFence removes a markdown code fence around code.
+func trimCodeFence(code string) string {
+	code = strings.TrimSpace(code)
+	if !strings.HasPrefix(code, "```") || !strings.HasSuffix(code, "```") {
+		return code
+	}
+	code = strings.TrimSuffix(code, "```")
+	if _, rest, ok := strings.Cut(code, "\n"); ok {
+		return rest
+	}
+	return ""
+}
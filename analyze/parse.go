@@ -15,12 +15,18 @@ type SummaryResponse struct {
 
 // IssueResponse is a single issue from the LLM
 type IssueResponse struct {
-	Function   string `json:"function"`
-	Line       int    `json:"line"`
-	Code       string `json:"code"`
-	Severity   string `json:"severity"`
-	Message    string `json:"message"`
-	Suggestion string `json:"suggestion,omitempty"`
+	Function   string  `json:"function"`
+	Line       int     `json:"line"`
+	Code       string  `json:"code"`
+	Severity   string  `json:"severity"`
+	Message    string  `json:"message"`
+	Suggestion string  `json:"suggestion,omitempty"`
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// FixResponse is the expected JSON structure for fix generation
+type FixResponse struct {
+	Code string `json:"code"`
 }
 
 // IssuesResponse is the expected JSON structure for analysis passes
@@ -54,6 +60,18 @@ func ParseIssuesResponse(response string) ([]IssueResponse, error) {
 	return issues.Issues, nil
 }
 
+// ParseFixResponse parses the LLM response for fix generation
+func ParseFixResponse(response string) (*FixResponse, error) {
+	var fix FixResponse
+	if err := json.Unmarshal([]byte(response), &fix); err != nil {
+		return nil, &ParseError{
+			Err:      err,
+			Response: response,
+		}
+	}
+	return &fix, nil
+}
+
 // ParseError provides context when JSON parsing fails
 type ParseError struct {
 	Err      error
@@ -3,8 +3,11 @@ package analyze
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
+	"os"
+	"path/filepath"
 	"strings"
 	"text/template"
 
@@ -22,6 +25,16 @@ type ProgressCallback func(event ProgressEvent)
 type ProgressEvent struct {
 	Phase      string // "summary" or the analysis pass name
 	IssueFound *IssueEvent
+	Fallback   *FallbackEvent
+	// FixError is set when generating a fix failed, the issue is kept without a fix.
+	FixError error
+}
+
+// FallbackEvent is emitted when a model fails and the next model of a fallback chain is tried
+type FallbackEvent struct {
+	Model string
+	Next  string
+	Err   error
 }
 
 // IssueEvent is emitted when an issue is found
@@ -35,22 +48,38 @@ type Pipeline struct {
 	config        *config.Config
 	cache         *cache.Cache
 	llmClient     llm.Client
+	clients       map[string]llm.Client
 	prompts       map[string]*template.Template
+	issuesSchema  *llm.JSONSchema
 	summaries     map[string]*SummaryResponse
 	externalFuncs map[string]*extract.ExternalFunc
 	promptsFS     fs.FS
 	onProgress    ProgressCallback
+	overrides     []overridePatterns
+	workDir       string
+	checkFix      func(filename string, src []byte) error
+	examples      []Example
 }
 
-// NewPipeline creates a new analysis pipeline
+// NewPipeline creates a new analysis pipeline.
+// All requests are sent to client; when client is nil, an OpenAI-compatible
+// client is created for each configured LLM endpoint.
 func NewPipeline(cfg *config.Config, c *cache.Cache, client llm.Client, externalFuncs map[string]*extract.ExternalFunc) *Pipeline {
+	issuesSchema := IssuesSchema
+	if len(cfg.Severities) > 0 {
+		issuesSchema = NewIssuesSchema(cfg.Severities)
+	}
+	workDir, _ := os.Getwd()
 	return &Pipeline{
 		config:        cfg,
 		cache:         c,
 		llmClient:     client,
+		clients:       make(map[string]llm.Client),
 		prompts:       make(map[string]*template.Template),
+		issuesSchema:  issuesSchema,
 		summaries:     make(map[string]*SummaryResponse),
 		externalFuncs: externalFuncs,
+		workDir:       workDir,
 	}
 }
 
@@ -60,6 +89,19 @@ func (p *Pipeline) SetPromptsFS(fsys fs.FS) {
 	p.promptsFS = fsys
 }
 
+// SetWorkDir sets the directory that override paths are relative to,
+// the working directory by default.
+func (p *Pipeline) SetWorkDir(dir string) {
+	p.workDir = dir
+}
+
+// SetFixChecker sets a function that validates generated fixes, e.g. by
+// type-checking src as the new content of filename. Without a checker,
+// fixes are only parsed.
+func (p *Pipeline) SetFixChecker(check func(filename string, src []byte) error) {
+	p.checkFix = check
+}
+
 // OnProgress sets a callback for progress events during analysis.
 func (p *Pipeline) OnProgress(cb ProgressCallback) {
 	p.onProgress = cb
@@ -71,12 +113,20 @@ func (p *Pipeline) reportProgress(event ProgressEvent) {
 	}
 }
 
-// LoadPrompts loads all prompt templates from config
+// LoadPrompts loads all prompt templates from config, including those of
+// overrides, and compiles the override patterns.
 func (p *Pipeline) LoadPrompts() error {
-	for _, pass := range p.config.Analyse {
+	passes := p.config.AllPasses()
+	if p.config.Fix.Enabled {
+		passes = append(passes, p.config.Fix.Pass())
+	}
+	for _, pass := range passes {
 		if !pass.Enabled {
 			continue
 		}
+		if _, ok := p.prompts[pass.Prompt]; ok {
+			continue
+		}
 		var tmpl *template.Template
 		var err error
 
@@ -90,48 +140,88 @@ func (p *Pipeline) LoadPrompts() error {
 		if err != nil {
 			return fmt.Errorf("load prompt %s: %w", pass.Name, err)
 		}
-		p.prompts[pass.Name] = tmpl
+		p.prompts[pass.Prompt] = tmpl
+	}
+
+	p.overrides = p.overrides[:0]
+	for i, override := range p.config.Overrides {
+		patterns, err := extract.CompilePatterns(override.Match, nil)
+		if err != nil {
+			return fmt.Errorf("overrides[%d]: %w", i, err)
+		}
+		p.overrides = append(p.overrides, overridePatterns{override: override, patterns: patterns})
 	}
 	return nil
 }
 
-// Analyze runs all analysis passes on a single unit
-func (p *Pipeline) Analyze(ctx context.Context, unit *extract.AnalysisUnit, calleeSummaries map[string]*SummaryResponse) (*report.UnitReport, error) {
-	// Build prompt context
-	promptCtx := p.BuildPromptContext(unit, calleeSummaries)
-
-	// Check cache for summary
-	cacheKey := p.cacheKey(unit, calleeSummaries)
-	var summary *SummaryResponse
+// overridePatterns is an override with its compiled match patterns.
+type overridePatterns struct {
+	override config.Override
+	patterns extract.Patterns
+}
 
-	if p.config.Cache.Enabled {
-		if data, ok := p.cache.Get(cacheKey); ok {
-			if err := json.Unmarshal(data, &summary); err == nil {
-				p.summaries[unit.ID] = summary
+// UnitPasses returns the passes to run on unit, after applying the
+// overrides that match the package or file of any of its functions.
+func (p *Pipeline) UnitPasses(unit *extract.AnalysisUnit) []config.AnalysisPass {
+	var matched []config.Override
+	for _, o := range p.overrides {
+		for _, fn := range unit.Functions {
+			if o.patterns.Match(fn.Package, p.relativePath(fn.Position.Filename)) {
+				matched = append(matched, o.override)
+				break
 			}
 		}
 	}
+	return p.config.Passes(matched)
+}
 
-	// Run summary pass if not cached
-	if summary == nil {
-		p.reportProgress(ProgressEvent{Phase: "summary"})
-		var err error
-		summary, err = p.runSummaryPass(ctx, promptCtx)
-		if err != nil {
-			return nil, fmt.Errorf("summary pass for %s: %w", unit.ID, err)
+// relativePath returns path relative to the working directory when possible.
+func (p *Pipeline) relativePath(path string) string {
+	if p.workDir == "" {
+		return filepath.ToSlash(path)
+	}
+	rel, err := filepath.Rel(p.workDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// summaryPass returns the enabled summary pass of passes.
+func summaryPass(passes []config.AnalysisPass) (config.AnalysisPass, bool) {
+	for _, pass := range passes {
+		if pass.Name == "summary" && pass.Enabled {
+			return pass, true
 		}
-		p.summaries[unit.ID] = summary
+	}
+	return config.AnalysisPass{}, false
+}
 
-		// Cache the summary
-		if p.config.Cache.Enabled {
-			if data, err := json.Marshal(summary); err == nil {
-				p.cache.Set(cacheKey, data)
-			}
+// enabledPassNames returns the names of the enabled passes.
+func enabledPassNames(passes []config.AnalysisPass) []string {
+	var names []string
+	for _, pass := range passes {
+		if pass.Enabled {
+			names = append(names, pass.Name)
 		}
 	}
+	return names
+}
+
+// Analyze runs all analysis passes on a single unit
+func (p *Pipeline) Analyze(ctx context.Context, unit *extract.AnalysisUnit, calleeSummaries map[string]*SummaryResponse) (*report.UnitReport, error) {
+	// Build prompt context
+	promptCtx := p.BuildPromptContext(unit, calleeSummaries)
+	passes := p.UnitPasses(unit)
+
+	summary, err := p.Summarize(ctx, unit, calleeSummaries)
+	if err != nil {
+		return nil, err
+	}
 
 	// Build unit report
 	unitReport := &report.UnitReport{
+		Passes: enabledPassNames(passes),
 		Summary: report.FunctionSummary{
 			Purpose:    summary.Purpose,
 			Behavior:   summary.Behavior,
@@ -143,21 +233,19 @@ func (p *Pipeline) Analyze(ctx context.Context, unit *extract.AnalysisUnit, call
 	// Add function info
 	for _, fn := range unit.Functions {
 		unitReport.Functions = append(unitReport.Functions, report.FunctionInfo{
+			ID:        fn.ID(),
 			Package:   fn.Package,
 			Name:      fn.Name,
 			Receiver:  fn.Receiver,
 			Signature: fn.Signature,
 			Position:  fn.Position,
+			EndLine:   fn.EndLine(),
+			FileHash:  fn.FileHash,
 		})
 	}
 
 	// Add summary to prompt context for analysis passes
-	promptCtx.Summary = &SummaryContext{
-		Purpose:    summary.Purpose,
-		Behavior:   summary.Behavior,
-		Invariants: summary.Invariants,
-		Security:   summary.Security,
-	}
+	promptCtx.Summary = summaryContext(summary)
 
 	// Build function position lookup for converting relative line numbers
 	funcPositions := make(map[string]extract.FunctionInfo)
@@ -166,13 +254,15 @@ func (p *Pipeline) Analyze(ctx context.Context, unit *extract.AnalysisUnit, call
 	}
 
 	// Run analysis passes
-	for _, pass := range p.config.Analyse {
+	for _, pass := range passes {
 		if !pass.Enabled || pass.Name == "summary" {
 			continue
 		}
 
 		p.reportProgress(ProgressEvent{Phase: pass.Name})
-		issues, err := p.runAnalysisPass(ctx, pass, promptCtx)
+		passCtx := promptCtx
+		passCtx.Examples = p.passExamples(pass.Name, unit)
+		issues, model, err := p.runAnalysisPass(ctx, pass, passCtx)
 		if err != nil {
 			return nil, fmt.Errorf("%s pass for %s: %w", pass.Name, unit.ID, err)
 		}
@@ -209,21 +299,74 @@ func (p *Pipeline) Analyze(ctx context.Context, unit *extract.AnalysisUnit, call
 				pos.Line = pos.Line + issue.Line - 1
 			}
 
-			unitReport.Issues = append(unitReport.Issues, report.Issue{
+			// Prefer the source line over the code quoted by the LLM
+			snippet := strings.TrimSpace(issue.Code)
+			if line, ok := bodyLine(fn.Body, pos.Line-fn.Position.Line+1); ok {
+				snippet = line
+			}
+
+			reportIssue := report.Issue{
 				Position:   pos,
+				EndLine:    pos.Line,
+				Function:   fn.ID(),
 				Severity:   report.Severity(issue.Severity),
 				Category:   pass.Name,
 				Message:    issue.Message,
+				Snippet:    snippet,
 				Suggestion: issue.Suggestion,
-			})
+				Confidence: issue.Confidence,
+				Model:      model,
+			}
+			if p.shouldFix(reportIssue) {
+				p.reportProgress(ProgressEvent{Phase: "fix"})
+				fix, err := p.generateFix(ctx, promptCtx, fn, reportIssue)
+				if err != nil {
+					if ctx.Err() != nil {
+						return nil, ctx.Err()
+					}
+					p.reportProgress(ProgressEvent{Phase: "fix", FixError: fmt.Errorf("fix %s issue in %s: %w", pass.Name, fn.ID(), err)})
+				}
+				reportIssue.Fix = fix
+			}
+			unitReport.Issues = append(unitReport.Issues, reportIssue)
 		}
 	}
 
 	return unitReport, nil
 }
 
+// Summarize runs only the summary pass on a unit, using the cached summary when available.
+func (p *Pipeline) Summarize(ctx context.Context, unit *extract.AnalysisUnit, calleeSummaries map[string]*SummaryResponse) (*SummaryResponse, error) {
+	// Check cache for summary
+	if summary := p.CachedSummary(unit, calleeSummaries); summary != nil {
+		p.summaries[unit.ID] = summary
+		return summary, nil
+	}
+
+	p.reportProgress(ProgressEvent{Phase: "summary"})
+	summary, err := p.runSummaryPass(ctx, p.UnitPasses(unit), p.BuildPromptContext(unit, calleeSummaries))
+	if err != nil {
+		return nil, fmt.Errorf("summary pass for %s: %w", unit.ID, err)
+	}
+	p.summaries[unit.ID] = summary
+
+	// Cache the summary
+	if p.config.Cache.Enabled {
+		if data, err := json.Marshal(summary); err == nil {
+			p.cache.Set(p.cacheKey(unit, calleeSummaries), data)
+		}
+	}
+	return summary, nil
+}
+
 func (p *Pipeline) BuildPromptContext(unit *extract.AnalysisUnit, calleeSummaries map[string]*SummaryResponse) PromptContext {
 	ctx := PromptContext{}
+	for _, level := range p.config.IssueSeverities() {
+		ctx.Severities = append(ctx.Severities, SeverityContext{
+			Name:        string(level.Name),
+			Description: level.Description,
+		})
+	}
 
 	if len(unit.Functions) == 1 {
 		fn := unit.Functions[0]
@@ -260,13 +403,15 @@ func (p *Pipeline) BuildPromptContext(unit *extract.AnalysisUnit, calleeSummarie
 	}
 
 	// Add external function info
-	for _, calleeID := range unit.Callees {
+	for _, calleeID := range unit.External {
 		if ext, ok := p.externalFuncs[calleeID]; ok {
 			ctx.ExternalFuncs = append(ctx.ExternalFuncs, ExternalFuncContext{
-				Package:   ext.Package,
-				Name:      ext.Name,
-				Signature: ext.Signature,
-				Godoc:     ext.Godoc,
+				Package:    ext.Package,
+				Name:       ext.Name,
+				Signature:  ext.Signature,
+				Godoc:      ext.Godoc,
+				Invariants: ext.Invariants,
+				Pitfalls:   ext.Pitfalls,
 			})
 		}
 	}
@@ -274,73 +419,186 @@ func (p *Pipeline) BuildPromptContext(unit *extract.AnalysisUnit, calleeSummarie
 	return ctx
 }
 
-func (p *Pipeline) runSummaryPass(ctx context.Context, promptCtx PromptContext) (*SummaryResponse, error) {
-	tmpl, ok := p.prompts["summary"]
-	if !ok {
-		return nil, fmt.Errorf("summary prompt not loaded")
+// summaryContext converts a summary response for use in prompts.
+func summaryContext(summary *SummaryResponse) *SummaryContext {
+	return &SummaryContext{
+		Purpose:    summary.Purpose,
+		Behavior:   summary.Behavior,
+		Invariants: summary.Invariants,
+		Security:   summary.Security,
 	}
+}
 
-	prompt, err := ExecutePrompt(tmpl, promptCtx)
-	if err != nil {
-		return nil, err
+// PassPrompt is the rendered prompt of a single pass.
+type PassPrompt struct {
+	Pass   string
+	Prompt string
+	Schema *llm.JSONSchema
+}
+
+// RenderPrompts renders the prompt of every enabled pass for unit, without calling the LLM.
+// summary is included in the analysis pass prompts, it may be nil when the unit has not been summarized.
+func (p *Pipeline) RenderPrompts(unit *extract.AnalysisUnit, calleeSummaries map[string]*SummaryResponse, summary *SummaryResponse) ([]PassPrompt, error) {
+	promptCtx := p.BuildPromptContext(unit, calleeSummaries)
+	passes := p.UnitPasses(unit)
+
+	var prompts []PassPrompt
+
+	// The summary pass always runs first
+	if pass, ok := summaryPass(passes); ok {
+		tmpl, ok := p.prompts[pass.Prompt]
+		if !ok {
+			return nil, fmt.Errorf("prompt %s not loaded", pass.Name)
+		}
+		prompt, err := ExecutePrompt(tmpl, promptCtx)
+		if err != nil {
+			return nil, fmt.Errorf("summary prompt for %s: %w", unit.ID, err)
+		}
+		prompts = append(prompts, PassPrompt{Pass: "summary", Prompt: prompt, Schema: SummarySchema})
 	}
 
-	// Find LLM config for summary pass
-	llmCfg := p.config.LLM
-	for _, pass := range p.config.Analyse {
-		if pass.Name == "summary" && pass.LLM != nil {
-			llmCfg = *pass.LLM
-			break
+	if summary != nil {
+		promptCtx.Summary = summaryContext(summary)
+	}
+	for _, pass := range passes {
+		if !pass.Enabled || pass.Name == "summary" {
+			continue
+		}
+		tmpl, ok := p.prompts[pass.Prompt]
+		if !ok {
+			return nil, fmt.Errorf("prompt %s not loaded", pass.Name)
 		}
+		passCtx := promptCtx
+		passCtx.Examples = p.passExamples(pass.Name, unit)
+		prompt, err := ExecutePrompt(tmpl, passCtx)
+		if err != nil {
+			return nil, fmt.Errorf("%s prompt for %s: %w", pass.Name, unit.ID, err)
+		}
+		prompts = append(prompts, PassPrompt{Pass: pass.Name, Prompt: prompt, Schema: p.issuesSchema})
 	}
 
-	resp, err := p.llmClient.Complete(ctx, llm.Request{
-		Messages: []llm.Message{{Role: "user", Content: prompt}},
-		Config: llm.ModelConfig{
-			Model:       llmCfg.Model,
-			MaxTokens:   llmCfg.MaxTokens,
-			Temperature: llmCfg.Temperature,
-			JSONSchema:  SummarySchema,
-		},
-	})
+	return prompts, nil
+}
+
+// EstimateTokens roughly estimates the number of tokens in text,
+// assuming about four bytes per token.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+func (p *Pipeline) runSummaryPass(ctx context.Context, passes []config.AnalysisPass, promptCtx PromptContext) (*SummaryResponse, error) {
+	pass, ok := summaryPass(passes)
+	if !ok {
+		return nil, fmt.Errorf("no enabled summary pass")
+	}
+	tmpl, ok := p.prompts[pass.Prompt]
+	if !ok {
+		return nil, fmt.Errorf("summary prompt not loaded")
+	}
+
+	prompt, err := ExecutePrompt(tmpl, promptCtx)
 	if err != nil {
 		return nil, err
 	}
 
-	return ParseSummaryResponse(resp.Content)
+	var summary *SummaryResponse
+	_, err = p.complete(ctx, pass, prompt, SummarySchema, func(content string) (err error) {
+		summary, err = ParseSummaryResponse(content)
+		return err
+	})
+	return summary, err
 }
 
-func (p *Pipeline) runAnalysisPass(ctx context.Context, pass config.AnalysisPass, promptCtx PromptContext) ([]IssueResponse, error) {
-	tmpl, ok := p.prompts[pass.Name]
+// runAnalysisPass returns the issues found by pass and the model that found them.
+func (p *Pipeline) runAnalysisPass(ctx context.Context, pass config.AnalysisPass, promptCtx PromptContext) ([]IssueResponse, string, error) {
+	tmpl, ok := p.prompts[pass.Prompt]
 	if !ok {
-		return nil, fmt.Errorf("prompt %s not loaded", pass.Name)
+		return nil, "", fmt.Errorf("prompt %s not loaded", pass.Name)
 	}
 
 	prompt, err := ExecutePrompt(tmpl, promptCtx)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	// Use pass-specific LLM config or default
-	llmCfg := p.config.LLM
-	if pass.LLM != nil {
-		llmCfg = *pass.LLM
+	var issues []IssueResponse
+	model, err := p.complete(ctx, pass, prompt, p.issuesSchema, func(content string) (err error) {
+		issues, err = ParseIssuesResponse(content)
+		return err
+	})
+	return issues, model, err
+}
+
+// complete sends prompt to the models of pass, parses the response and
+// returns the model that responded.
+// Models of a fallback chain are tried in order, moving on when the prompt
+// does not fit the context window, the request fails or the response
+// cannot be parsed.
+func (p *Pipeline) complete(ctx context.Context, pass config.AnalysisPass, prompt string, schema *llm.JSONSchema, parse func(content string) error) (model string, err error) {
+	models := p.config.PassLLMs(pass)
+
+	var errs []error
+	for i, llmCfg := range models {
+		err := p.completeWith(ctx, llmCfg, prompt, schema, parse)
+		if err == nil {
+			return llmCfg.Model, nil
+		}
+		if ctx.Err() != nil {
+			return "", err
+		}
+		errs = append(errs, fmt.Errorf("model %s: %w", llmCfg.Model, err))
+
+		if i+1 < len(models) {
+			p.reportProgress(ProgressEvent{
+				Phase: pass.Name,
+				Fallback: &FallbackEvent{
+					Model: llmCfg.Model,
+					Next:  models[i+1].Model,
+					Err:   err,
+				},
+			})
+		}
 	}
+	return "", errors.Join(errs...)
+}
 
-	resp, err := p.llmClient.Complete(ctx, llm.Request{
+// completeWith sends prompt to a single model and parses the response.
+func (p *Pipeline) completeWith(ctx context.Context, llmCfg config.LLMConfig, prompt string, schema *llm.JSONSchema, parse func(content string) error) error {
+	if llmCfg.ContextWindow > 0 {
+		if tokens := EstimateTokens(prompt); tokens > llmCfg.ContextWindow {
+			return fmt.Errorf("prompt of about %d tokens exceeds the context window of %d tokens", tokens, llmCfg.ContextWindow)
+		}
+	}
+
+	resp, err := p.client(llmCfg).Complete(ctx, llm.Request{
 		Messages: []llm.Message{{Role: "user", Content: prompt}},
 		Config: llm.ModelConfig{
 			Model:       llmCfg.Model,
 			MaxTokens:   llmCfg.MaxTokens,
 			Temperature: llmCfg.Temperature,
-			JSONSchema:  IssuesSchema,
+			JSONSchema:  schema,
 		},
 	})
 	if err != nil {
-		return nil, err
+		return err
+	}
+	return parse(resp.Content)
+}
+
+// client returns the client for llmCfg.
+// Clients are shared between configurations with the same endpoint and key.
+func (p *Pipeline) client(llmCfg config.LLMConfig) llm.Client {
+	if p.llmClient != nil {
+		return p.llmClient
 	}
 
-	return ParseIssuesResponse(resp.Content)
+	key := llmCfg.BaseURL + "\x00" + llmCfg.APIKey
+	client, ok := p.clients[key]
+	if !ok {
+		client = llm.NewOpenAIClient(llmCfg.BaseURL, llmCfg.APIKey)
+		p.clients[key] = client
+	}
+	return client
 }
 
 func (p *Pipeline) cacheKey(unit *extract.AnalysisUnit, calleeSummaries map[string]*SummaryResponse) string {
@@ -357,6 +615,24 @@ func (p *Pipeline) cacheKey(unit *extract.AnalysisUnit, calleeSummaries map[stri
 	return cache.ContentHash(parts...)
 }
 
+// CachedSummary returns the cached summary of unit, or nil when caching is
+// disabled or the summary is not cached. The cache key depends on the callee
+// summaries, so they must be the same as the ones used during analysis.
+func (p *Pipeline) CachedSummary(unit *extract.AnalysisUnit, calleeSummaries map[string]*SummaryResponse) *SummaryResponse {
+	if !p.config.Cache.Enabled || p.cache == nil {
+		return nil
+	}
+	data, ok := p.cache.Get(p.cacheKey(unit, calleeSummaries))
+	if !ok {
+		return nil
+	}
+	var summary *SummaryResponse
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil
+	}
+	return summary
+}
+
 // GetSummary returns the summary for a unit
 func (p *Pipeline) GetSummary(unitID string) *SummaryResponse {
 	return p.summaries[unitID]
@@ -416,6 +692,16 @@ func findLineInBody(body, code string, hintLine int) int {
 	return best
 }
 
+// bodyLine returns the n-th line of body, starting from 1, without surrounding whitespace.
+func bodyLine(body string, n int) (string, bool) {
+	lines := strings.Split(body, "\n")
+	if n < 1 || n > len(lines) {
+		return "", false
+	}
+	line := strings.TrimSpace(lines[n-1])
+	return line, line != ""
+}
+
 func abs(x int) int {
 	if x < 0 {
 		return -x
@@ -0,0 +1,507 @@
+package analyze
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/loov/dreamlint/cache"
+	"github.com/loov/dreamlint/config"
+	"github.com/loov/dreamlint/extract"
+	"github.com/loov/dreamlint/llm"
+	"github.com/loov/dreamlint/report"
+)
+
+func testPipeline(t *testing.T, c *cache.Cache, client llm.Client) *Pipeline {
+	t.Helper()
+	cfg := &config.Config{
+		Cache: config.CacheConfig{Enabled: c != nil},
+		Analyse: []config.AnalysisPass{
+			{Name: "summary", Prompt: "builtin:summary", Enabled: true},
+			{Name: "correctness", Prompt: "builtin:correctness", Enabled: true},
+			{Name: "security", Prompt: "builtin:security", Enabled: false},
+		},
+	}
+	p := NewPipeline(cfg, c, client, nil)
+	if err := p.LoadPrompts(); err != nil {
+		t.Fatalf("LoadPrompts: %v", err)
+	}
+	return p
+}
+
+func testUnit() *extract.AnalysisUnit {
+	return &extract.AnalysisUnit{
+		ID: "pkg.Add",
+		Functions: []*extract.FunctionInfo{{
+			Package:   "pkg",
+			Name:      "Add",
+			Signature: "func Add(a, b int) int",
+			Body:      "func Add(a, b int) int {\n\treturn a + b\n}",
+		}},
+	}
+}
+
+func TestPipeline_RenderPrompts(t *testing.T) {
+	client := llm.NewMockClient(
+		llm.Response{Content: `{"purpose": "adds", "behavior": "returns a+b"}`},
+		llm.Response{Content: `{"issues": []}`},
+	)
+	p := testPipeline(t, nil, client)
+	unit := testUnit()
+
+	if _, err := p.Analyze(context.Background(), unit, nil); err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	prompts, err := p.RenderPrompts(unit, nil, p.GetSummary(unit.ID))
+	if err != nil {
+		t.Fatalf("RenderPrompts: %v", err)
+	}
+
+	sent := client.Prompts()
+	if len(prompts) != len(sent) {
+		t.Fatalf("got %d prompts, want %d", len(prompts), len(sent))
+	}
+	for i, prompt := range prompts {
+		if prompt.Prompt != sent[i] {
+			t.Errorf("%s prompt differs from the prompt sent to the LLM", prompt.Pass)
+		}
+	}
+	if prompts[0].Pass != "summary" || prompts[0].Schema != SummarySchema {
+		t.Errorf("first prompt = %s, want summary with SummarySchema", prompts[0].Pass)
+	}
+	if prompts[1].Pass != "correctness" || prompts[1].Schema != IssuesSchema {
+		t.Errorf("second prompt = %s, want correctness with IssuesSchema", prompts[1].Pass)
+	}
+}
+
+func TestPipeline_CachedSummary(t *testing.T) {
+	c := cache.New(t.TempDir())
+	client := llm.NewMockClient(
+		llm.Response{Content: `{"purpose": "adds", "behavior": "returns a+b"}`},
+		llm.Response{Content: `{"issues": []}`},
+	)
+	p := testPipeline(t, c, client)
+	unit := testUnit()
+
+	if summary := p.CachedSummary(unit, nil); summary != nil {
+		t.Fatalf("CachedSummary before analysis = %+v, want nil", summary)
+	}
+	if _, err := p.Analyze(context.Background(), unit, nil); err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	summary := p.CachedSummary(unit, nil)
+	if summary == nil || summary.Purpose != "adds" {
+		t.Fatalf("CachedSummary after analysis = %+v, want purpose \"adds\"", summary)
+	}
+
+	// Different callee summaries change the cache key
+	unit.Callees = []string{"pkg.Other"}
+	callees := map[string]*SummaryResponse{"pkg.Other": {Purpose: "other"}}
+	if summary := p.CachedSummary(unit, callees); summary != nil {
+		t.Errorf("CachedSummary with new callee summaries = %+v, want nil", summary)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"abcd", 1},
+		{"abcde", 2},
+	}
+	for _, test := range tests {
+		if got := EstimateTokens(test.text); got != test.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", test.text, got, test.want)
+		}
+	}
+}
+
+func TestPipeline_Fallback(t *testing.T) {
+	client := llm.NewMockClient(
+		llm.Response{Content: `not json`},
+		llm.Response{Content: `{"purpose": "adds", "behavior": "returns a+b"}`},
+	)
+	cfg := &config.Config{
+		LLM: config.LLMConfig{Model: "default"},
+		Models: map[string]config.LLMConfig{
+			"tiny":   {Model: "tiny", ContextWindow: 10},
+			"broken": {Model: "broken"},
+			"good":   {Model: "good"},
+		},
+		Analyse: []config.AnalysisPass{
+			{Name: "summary", Prompt: "builtin:summary", Enabled: true, Models: []string{"tiny", "broken", "good"}},
+		},
+	}
+	p := NewPipeline(cfg, nil, client, nil)
+	if err := p.LoadPrompts(); err != nil {
+		t.Fatalf("LoadPrompts: %v", err)
+	}
+
+	var fallbacks []string
+	p.OnProgress(func(event ProgressEvent) {
+		if event.Fallback != nil {
+			fallbacks = append(fallbacks, event.Fallback.Model+"->"+event.Fallback.Next)
+		}
+	})
+
+	summary, err := p.Summarize(context.Background(), testUnit(), nil)
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	if summary.Purpose != "adds" {
+		t.Errorf("purpose = %q, want %q", summary.Purpose, "adds")
+	}
+
+	// tiny is skipped without a request, because the prompt does not fit
+	var models []string
+	for _, req := range client.Requests() {
+		models = append(models, req.Request.Config.Model)
+	}
+	if !slices.Equal(models, []string{"broken", "good"}) {
+		t.Errorf("requested models = %v, want [broken good]", models)
+	}
+	if !slices.Equal(fallbacks, []string{"tiny->broken", "broken->good"}) {
+		t.Errorf("fallbacks = %v, want [tiny->broken broken->good]", fallbacks)
+	}
+
+	// All models failing reports each error
+	client = llm.NewMockClient(llm.Response{Content: `not json`}, llm.Response{Content: `not json`})
+	p = NewPipeline(cfg, nil, client, nil)
+	if err := p.LoadPrompts(); err != nil {
+		t.Fatalf("LoadPrompts: %v", err)
+	}
+	_, err = p.Summarize(context.Background(), testUnit(), nil)
+	if err == nil {
+		t.Fatal("Summarize succeeded, want error")
+	}
+	for _, model := range []string{"tiny", "broken", "good"} {
+		if !strings.Contains(err.Error(), "model "+model) {
+			t.Errorf("error %q does not mention model %s", err, model)
+		}
+	}
+}
+
+func TestPipeline_Overrides(t *testing.T) {
+	client := llm.NewMockClient(
+		llm.Response{Content: `{"purpose": "adds", "behavior": "returns a+b"}`},
+		llm.Response{Content: `{"issues": []}`},
+	)
+	cfg := &config.Config{
+		LLM:    config.LLMConfig{Model: "default"},
+		Models: map[string]config.LLMConfig{"strong": {Model: "strong"}},
+		Analyse: []config.AnalysisPass{
+			{Name: "summary", Prompt: "builtin:summary", Enabled: true},
+			{Name: "correctness", Prompt: "builtin:correctness", Enabled: true},
+		},
+		Overrides: []config.Override{
+			{Match: []string{"other/..."}, Models: []string{"missing"}},
+			{Match: []string{"pkg"}, Analyse: []config.AnalysisPass{
+				{Name: "summary", Prompt: "builtin:summary", Enabled: true},
+				{Name: "security", Prompt: "builtin:security", Enabled: true},
+			}},
+			{Match: []string{"/add\\.go$/"}, Models: []string{"strong"}},
+		},
+	}
+	p := NewPipeline(cfg, nil, client, nil)
+	if err := p.LoadPrompts(); err != nil {
+		t.Fatalf("LoadPrompts: %v", err)
+	}
+
+	unit := testUnit()
+	unit.Functions[0].Position.Filename = "pkg/add.go"
+	unitReport, err := p.Analyze(context.Background(), unit, nil)
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if want := []string{"summary", "security"}; !slices.Equal(unitReport.Passes, want) {
+		t.Errorf("passes = %v, want %v", unitReport.Passes, want)
+	}
+
+	var models []string
+	for _, req := range client.Requests() {
+		models = append(models, req.Request.Config.Model)
+	}
+	if want := []string{"strong", "strong"}; !slices.Equal(models, want) {
+		t.Errorf("requested models = %v, want %v", models, want)
+	}
+}
+
+func TestPipeline_WorkDir(t *testing.T) {
+	cfg := &config.Config{
+		Analyse: []config.AnalysisPass{
+			{Name: "summary", Prompt: "builtin:summary", Enabled: true},
+		},
+		Overrides: []config.Override{
+			{Match: []string{"/^pkg/add\\.go$/"}, Analyse: []config.AnalysisPass{
+				{Name: "summary", Prompt: "builtin:summary", Enabled: true},
+				{Name: "security", Prompt: "builtin:security", Enabled: true},
+			}},
+		},
+	}
+	p := NewPipeline(cfg, nil, nil, nil)
+	if err := p.LoadPrompts(); err != nil {
+		t.Fatalf("LoadPrompts: %v", err)
+	}
+
+	dir := t.TempDir()
+	unit := testUnit()
+	unit.Functions[0].Position.Filename = filepath.Join(dir, "pkg", "add.go")
+	if passes := p.UnitPasses(unit); len(passes) != 1 {
+		t.Errorf("override matched outside of the work dir: %v", passes)
+	}
+
+	p.SetWorkDir(dir)
+	if passes := p.UnitPasses(unit); len(passes) != 2 {
+		t.Errorf("override did not match within the work dir: %v", passes)
+	}
+}
+
+func TestPipeline_Severities(t *testing.T) {
+	cfg := &config.Config{
+		Analyse: []config.AnalysisPass{
+			{Name: "summary", Prompt: "builtin:summary", Enabled: true},
+			{Name: "correctness", Prompt: "builtin:correctness", Enabled: true},
+		},
+		Severities: report.Severities{
+			{Name: "blocker", Description: "must be fixed", SARIF: report.SARIFError},
+			{Name: "nit", SARIF: report.SARIFNote},
+		},
+	}
+	p := NewPipeline(cfg, nil, nil, nil)
+	if err := p.LoadPrompts(); err != nil {
+		t.Fatalf("LoadPrompts: %v", err)
+	}
+
+	prompts, err := p.RenderPrompts(testUnit(), nil, &SummaryResponse{Purpose: "adds"})
+	if err != nil {
+		t.Fatalf("RenderPrompts: %v", err)
+	}
+	issues := prompts[1]
+	if !strings.Contains(issues.Prompt, `"blocker": must be fixed`) || !strings.Contains(issues.Prompt, `"severity": "blocker"`) {
+		t.Errorf("prompt does not describe the configured severities:\n%s", issues.Prompt)
+	}
+	data, err := json.Marshal(issues.Schema.Schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"enum":["blocker","nit"]`) {
+		t.Errorf("schema does not restrict severities: %s", data)
+	}
+}
+
+func TestPipeline_IssueDetails(t *testing.T) {
+	client := llm.NewMockClient(
+		llm.Response{Content: `{"purpose": "adds", "behavior": "returns a+b"}`},
+		llm.Response{Content: `{"issues": [{"function": "Add", "line": 1, "code": "return a+b", "severity": "low", "message": "may overflow", "confidence": 0.4}]}`},
+	)
+	p := testPipeline(t, nil, client)
+	p.config.LLM.Model = "m"
+	unit := testUnit()
+	unit.Functions[0].Position = token.Position{Filename: "add.go", Line: 10}
+	// The code quoted by the LLM differs in spacing from the source
+	unit.Functions[0].Body = "func Add(a, b int) int {\n\treturn a+b // sum\n}"
+
+	unitReport, err := p.Analyze(context.Background(), unit, nil)
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if len(unitReport.Issues) != 1 {
+		t.Fatalf("got %d issues, want 1", len(unitReport.Issues))
+	}
+	issue := unitReport.Issues[0]
+	if issue.Position.Line != 11 || issue.EndLine != 11 {
+		t.Errorf("issue lines = %d-%d, want 11-11", issue.Position.Line, issue.EndLine)
+	}
+	if issue.Snippet != "return a+b // sum" {
+		t.Errorf("snippet = %q, want the source line", issue.Snippet)
+	}
+	if issue.Function != "pkg.Add" || issue.Confidence != 0.4 || issue.Model != "m" {
+		t.Errorf("issue = %+v", issue)
+	}
+	if fn := unitReport.Functions[0]; fn.EndLine != 12 {
+		t.Errorf("function end line = %d, want 12", fn.EndLine)
+	}
+}
+
+func TestPipeline_Fix(t *testing.T) {
+	const source = "package pkg\n\n// Div divides a by b.\nfunc Div(a, b int) int {\n\treturn a / b\n}\n"
+	const fixed = "// Div divides a by b, returning 0 when b is 0.\nfunc Div(a, b int) int {\n\tif b == 0 {\n\t\treturn 0\n\t}\n\treturn a / b\n}"
+
+	filename := filepath.Join(t.TempDir(), "div.go")
+	if err := os.WriteFile(filename, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	unit := &extract.AnalysisUnit{
+		ID: "pkg.Div",
+		Functions: []*extract.FunctionInfo{{
+			Package:   "pkg",
+			Name:      "Div",
+			Signature: "func Div(a, b int) int",
+			Body:      source[strings.Index(source, "// Div") : strings.LastIndex(source, "}")+1],
+			Position:  token.Position{Filename: filename, Offset: strings.Index(source, "// Div"), Line: 3, Column: 1},
+		}},
+	}
+
+	tests := []struct {
+		name    string
+		fix     string
+		wantErr string
+	}{
+		{"valid", fixed, ""},
+		{"fenced", "```go\n" + fixed + "\n```", ""},
+		{"syntax error", "func Div(a, b int) int {", "does not parse"},
+		{"unchanged", unit.Functions[0].Body, "does not change"},
+		{"rejected by checker", strings.Replace(fixed, "return 0", "return undefined", 1), "does not type-check"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fixJSON, err := json.Marshal(FixResponse{Code: test.fix})
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := llm.NewMockClient(
+				llm.Response{Content: `{"purpose": "divides", "behavior": "returns a/b"}`},
+				llm.Response{Content: `{"issues": [{"function": "Div", "line": 5, "code": "return a / b", "severity": "high", "message": "division by zero"}, {"function": "Div", "line": 5, "code": "return a / b", "severity": "low", "message": "unclear"}]}`},
+				llm.Response{Content: string(fixJSON)},
+			)
+			p := testPipeline(t, nil, client)
+			p.config.Fix = config.FixConfig{Enabled: true, Prompt: "builtin:fix", MinSeverity: "medium"}
+			if err := p.LoadPrompts(); err != nil {
+				t.Fatalf("LoadPrompts: %v", err)
+			}
+			p.SetFixChecker(func(filename string, src []byte) error {
+				if strings.Contains(string(src), "undefined") {
+					return errors.New("undefined: undefined")
+				}
+				return nil
+			})
+			var fixErr error
+			p.OnProgress(func(event ProgressEvent) {
+				if event.FixError != nil {
+					fixErr = event.FixError
+				}
+			})
+
+			unitReport, err := p.Analyze(context.Background(), unit, nil)
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			if len(client.Requests()) != 3 {
+				t.Errorf("got %d requests, want a fix only for the high severity issue", len(client.Requests()))
+			}
+			if low := unitReport.Issues[1]; low.Fix != nil {
+				t.Errorf("low severity issue has a fix")
+			}
+
+			fix := unitReport.Issues[0].Fix
+			if test.wantErr != "" {
+				if fix != nil || fixErr == nil || !strings.Contains(fixErr.Error(), test.wantErr) {
+					t.Errorf("fix = %v, error = %v, want error containing %q", fix, fixErr, test.wantErr)
+				}
+				return
+			}
+			if fixErr != nil {
+				t.Fatalf("fix error: %v", fixErr)
+			}
+			if fix.Replacement != fixed || fix.StartLine != 3 || fix.EndLine != 6 || fix.EndColumn != 2 {
+				t.Errorf("fix = %+v", fix)
+			}
+			if !strings.Contains(fix.Diff, "+\tif b == 0 {") || !strings.Contains(fix.Diff, "--- "+filename) {
+				t.Errorf("diff does not add the check:\n%s", fix.Diff)
+			}
+		})
+	}
+}
+
+func TestPipeline_FixIssue(t *testing.T) {
+	const source = "package pkg\n\nfunc Half(n int) int {\n\treturn n / 2\n}\n"
+	filename := filepath.Join(t.TempDir(), "half.go")
+	if err := os.WriteFile(filename, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fn := &extract.FunctionInfo{
+		Package:  "pkg",
+		Name:     "Half",
+		Body:     strings.TrimSpace(strings.TrimPrefix(source, "package pkg\n")),
+		Position: token.Position{Filename: filename, Offset: len("package pkg\n\n"), Line: 3, Column: 1},
+	}
+	unit := &extract.AnalysisUnit{ID: "pkg.Half", Functions: []*extract.FunctionInfo{fn}}
+
+	client := llm.NewMockClient(llm.Response{Content: `{"code": "func Half(n int) int {\n\treturn n >> 1\n}"}`})
+	p := testPipeline(t, nil, client)
+	p.config.Fix = config.FixConfig{Enabled: true, Prompt: "builtin:fix"}
+	if err := p.LoadPrompts(); err != nil {
+		t.Fatalf("LoadPrompts: %v", err)
+	}
+
+	summaries := map[string]*SummaryResponse{"pkg.Half": {Purpose: "halves n"}}
+	if _, err := p.Fix(context.Background(), unit, summaries, report.Issue{Function: "pkg.Other"}); err == nil {
+		t.Error("Fix of an issue outside of the unit succeeded")
+	}
+
+	fix, err := p.Fix(context.Background(), unit, summaries, report.Issue{Function: "pkg.Half", Severity: report.SeverityLow, Message: "rounds toward zero"})
+	if err != nil {
+		t.Fatalf("Fix: %v", err)
+	}
+	if !strings.Contains(fix.Diff, "+\treturn n >> 1") {
+		t.Errorf("diff:\n%s", fix.Diff)
+	}
+	messages := client.Requests()[0].Request.Messages
+	prompt := messages[len(messages)-1].Content
+	if !strings.Contains(prompt, "halves n") || !strings.Contains(prompt, "rounds toward zero") {
+		t.Errorf("prompt does not include the summary and the issue:\n%s", prompt)
+	}
+}
+
+func TestPipeline_Examples(t *testing.T) {
+	p := testPipeline(t, nil, nil)
+	p.config.Examples.Max = 2
+	p.SetExamples([]Example{
+		{Unit: "other.Format", Category: "correctness", Code: "fmt.Sprintf(format, args)", Message: "unrelated", Valid: true},
+		{Unit: "pkg.Sub", Category: "correctness", Code: "return a + b", Message: "overflow in same package", Valid: true},
+		{Unit: "pkg.Add", Category: "security", Code: "return a + b", Message: "other pass"},
+		{Unit: "pkg.Add", Category: "correctness", Code: "return a + b", Message: "overflow is expected"},
+	})
+
+	prompts, err := p.RenderPrompts(testUnit(), nil, &SummaryResponse{Purpose: "adds"})
+	if err != nil {
+		t.Fatalf("RenderPrompts: %v", err)
+	}
+	if len(prompts) != 2 || prompts[1].Pass != "correctness" {
+		t.Fatalf("got prompts %v", prompts)
+	}
+	prompt := prompts[1].Prompt
+	if strings.Contains(prompts[0].Prompt, "Reviewed Findings") {
+		t.Error("summary prompt includes examples")
+	}
+
+	// Examples of the same unit come first, then those of the same package
+	sameUnit := strings.Index(prompt, "False positive: overflow is expected")
+	samePackage := strings.Index(prompt, "Confirmed issue: overflow in same package")
+	if sameUnit < 0 || samePackage < 0 || sameUnit > samePackage {
+		t.Errorf("prompt does not list the most similar examples in order:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "unrelated") || strings.Contains(prompt, "other pass") {
+		t.Errorf("prompt includes examples beyond the limit or of other passes:\n%s", prompt)
+	}
+
+	p.config.Examples.Max = 0
+	prompts, err = p.RenderPrompts(testUnit(), nil, nil)
+	if err != nil {
+		t.Fatalf("RenderPrompts: %v", err)
+	}
+	if strings.Contains(prompts[1].Prompt, "Reviewed Findings") {
+		t.Error("examples included when disabled")
+	}
+}
@@ -32,6 +32,36 @@ type PromptContext struct {
 
 	// For non-summary passes
 	Summary *SummaryContext
+
+	// Issue severities from the most to the least severe
+	Severities []SeverityContext
+
+	// For fix generation, the issue to fix
+	Issue *IssueContext
+
+	// For analysis passes, reviewed findings of the pass
+	Examples []ExampleContext
+}
+
+// ExampleContext is a reviewed finding used as a few-shot example
+type ExampleContext struct {
+	Code    string
+	Message string
+	Valid   bool // confirmed issue rather than a false positive
+}
+
+// IssueContext describes an issue to fix
+type IssueContext struct {
+	Severity   string
+	Code       string
+	Message    string
+	Suggestion string
+}
+
+// SeverityContext describes an issue severity
+type SeverityContext struct {
+	Name        string
+	Description string
 }
 
 // FunctionContext holds info about a single function in an SCC
@@ -54,3 +54,34 @@ func TestExecutePrompt(t *testing.T) {
 		t.Errorf("result should contain function body")
 	}
 }
+
+func TestExecutePrompt_ExternalFuncs(t *testing.T) {
+	tmpl, err := LoadPrompt("builtin:concurrency")
+	if err != nil {
+		t.Fatalf("LoadPrompt: %v", err)
+	}
+
+	ctx := PromptContext{
+		Name: "Run",
+		Body: `func Run() { var wg sync.WaitGroup; go func() { wg.Add(1) }(); wg.Wait() }`,
+		ExternalFuncs: []ExternalFuncContext{{
+			Package:    "sync",
+			Name:       "Add",
+			Signature:  "func (*WaitGroup) Add(delta int)",
+			Invariants: []string{"Add must happen before Wait"},
+			Pitfalls:   []string{"Calling Add inside the goroutine races with Wait"},
+		}},
+	}
+
+	result, err := ExecutePrompt(tmpl, ctx)
+	if err != nil {
+		t.Fatalf("ExecutePrompt: %v", err)
+	}
+
+	if !strings.Contains(result, "- Add must happen before Wait") {
+		t.Errorf("result should contain external invariants")
+	}
+	if !strings.Contains(result, "Known Pitfalls:\n- Calling Add inside the goroutine races with Wait") {
+		t.Errorf("result should contain external pitfalls")
+	}
+}
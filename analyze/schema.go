@@ -35,49 +35,77 @@ var SummarySchema = &llm.JSONSchema{
 	},
 }
 
-// IssuesSchema is the JSON schema for analysis pass responses
-var IssuesSchema = &llm.JSONSchema{
-	Name: "issues",
+// FixSchema is the JSON schema for fix responses
+var FixSchema = &llm.JSONSchema{
+	Name: "fix",
 	Schema: map[string]any{
 		"type": "object",
 		"properties": map[string]any{
-			"issues": map[string]any{
-				"type": "array",
-				"items": map[string]any{
-					"type": "object",
-					"properties": map[string]any{
-						"function": map[string]any{
-							"type":        "string",
-							"description": "Name of the function where the issue occurs",
-						},
-						"line": map[string]any{
-							"type":        "integer",
-							"description": "Line number where the issue occurs",
-						},
-						"code": map[string]any{
-							"type":        "string",
-							"description": "The exact line of code where the issue occurs, copied verbatim from the code block",
-						},
-						"severity": map[string]any{
-							"type":        "string",
-							"enum":        report.SeverityStrings,
-							"description": "Severity of the issue",
-						},
-						"message": map[string]any{
-							"type":        "string",
-							"description": "Description of the issue",
-						},
-						"suggestion": map[string]any{
-							"type":        "string",
-							"description": "Suggested fix for the issue",
+			"code": map[string]any{
+				"type":        "string",
+				"description": "The complete corrected function declaration, including its doc comment",
+			},
+		},
+		"required":             []string{"code"},
+		"additionalProperties": false,
+	},
+}
+
+// IssuesSchema is the JSON schema for analysis pass responses with the default severities
+var IssuesSchema = NewIssuesSchema(report.DefaultSeverities)
+
+// NewIssuesSchema returns the JSON schema for analysis pass responses,
+// where the severity is one of severities.
+func NewIssuesSchema(severities report.Severities) *llm.JSONSchema {
+	return &llm.JSONSchema{
+		Name: "issues",
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"issues": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"function": map[string]any{
+								"type":        "string",
+								"description": "Name of the function where the issue occurs",
+							},
+							"line": map[string]any{
+								"type":        "integer",
+								"description": "Line number where the issue occurs",
+							},
+							"code": map[string]any{
+								"type":        "string",
+								"description": "The exact line of code where the issue occurs, copied verbatim from the code block",
+							},
+							"severity": map[string]any{
+								"type":        "string",
+								"enum":        severities.Names(),
+								"description": "Severity of the issue",
+							},
+							"message": map[string]any{
+								"type":        "string",
+								"description": "Description of the issue",
+							},
+							"suggestion": map[string]any{
+								"type":        "string",
+								"description": "Suggested fix for the issue",
+							},
+							"confidence": map[string]any{
+								"type":        "number",
+								"minimum":     0,
+								"maximum":     1,
+								"description": "Confidence that the issue is real, from 0 to 1",
+							},
 						},
+						"required":             []string{"function", "line", "code", "severity", "message"},
+						"additionalProperties": false,
 					},
-					"required":             []string{"function", "line", "code", "severity", "message"},
-					"additionalProperties": false,
 				},
 			},
+			"required":             []string{"issues"},
+			"additionalProperties": false,
 		},
-		"required":             []string{"issues"},
-		"additionalProperties": false,
-	},
+	}
 }
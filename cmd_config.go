@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/zeebo/clingy"
+
+	"github.com/loov/dreamlint/config"
+)
+
+// configFlags are the flags selecting the configuration.
+type configFlags struct {
+	configPaths   []string
+	inlineConfigs []string
+}
+
+func (c *configFlags) setup(params clingy.Parameters) {
+	c.configPaths = params.Flag("config", "path to config file",
+		[]string{"dreamlint.cue"},
+		clingy.Repeated,
+	).([]string)
+
+	c.inlineConfigs = params.Flag("c", "inline CUE config",
+		[]string{},
+		clingy.Repeated,
+	).([]string)
+}
+
+func (c *configFlags) load() (*config.Config, error) {
+	cfg, err := config.LoadConfig(c.configPaths, c.inlineConfigs)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	return cfg, nil
+}
+
+type cmdConfigShow struct {
+	configFlags
+	format string
+}
+
+func (c *cmdConfigShow) Setup(params clingy.Parameters) {
+	c.configFlags.setup(params)
+	c.format = params.Flag("format", "output format: cue or json", "cue").(string)
+}
+
+func (c *cmdConfigShow) Execute(ctx context.Context) error {
+	cfg, err := c.load()
+	if err != nil {
+		return err
+	}
+	cfg = cfg.Redacted()
+
+	switch c.format {
+	case "cue":
+		data, err := cfg.FormatCUE()
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(cfg)
+	default:
+		return fmt.Errorf("unknown format %q", c.format)
+	}
+}
+
+type cmdConfigValidate struct {
+	configFlags
+	promptsDir string
+}
+
+func (c *cmdConfigValidate) Setup(params clingy.Parameters) {
+	c.configFlags.setup(params)
+	c.promptsDir = params.Flag("prompts", "directory to load prompts from", "").(string)
+}
+
+func (c *cmdConfigValidate) Execute(ctx context.Context) error {
+	cfg, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	errs := []error{cfg.Validate()}
+	if _, err := buildFilter(cfg.Filter); err != nil {
+		errs = append(errs, fmt.Errorf("filter: %w", err))
+	}
+	if _, err := loadPipeline(cfg, nil, nil, c.promptsDir); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid config:\n%w", err)
+	}
+
+	enabled := 0
+	for _, pass := range cfg.Analyse {
+		if pass.Enabled {
+			enabled++
+		}
+	}
+	fmt.Printf("Config is valid: %d of %d passes enabled, model %s\n", enabled, len(cfg.Analyse), cfg.LLM.Model)
+	return nil
+}
+
+type cmdConfigSchema struct{}
+
+func (c *cmdConfigSchema) Setup(params clingy.Parameters) {}
+
+func (c *cmdConfigSchema) Execute(ctx context.Context) error {
+	_, err := fmt.Print(config.Schema())
+	return err
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/zeebo/clingy"
+
+	"github.com/loov/dreamlint/report"
+	"github.com/loov/dreamlint/report/markdown"
+)
+
+type cmdDiff struct {
+	format  string
+	oldPath string
+	newPath string
+}
+
+func (c *cmdDiff) Setup(params clingy.Parameters) {
+	c.format = params.Flag("format", "output format: text, markdown or json", "text").(string)
+
+	c.oldPath = params.Arg("old", "JSON report of the earlier run").(string)
+	c.newPath = params.Arg("new", "JSON report of the later run").(string)
+}
+
+func (c *cmdDiff) Execute(ctx context.Context) error {
+	if c.format != "text" && c.format != "markdown" && c.format != "json" {
+		return fmt.Errorf("unknown format %q", c.format)
+	}
+
+	old, err := report.ReadJSONFile(c.oldPath)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", c.oldPath, err)
+	}
+	newer, err := report.ReadJSONFile(c.newPath)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", c.newPath, err)
+	}
+
+	diff := report.Compare(old, newer)
+	switch c.format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(diff); err != nil {
+			return err
+		}
+	case "markdown":
+		fmt.Print(markdown.WriteDiff(diff, newer.Severities()))
+	default:
+		writeDiffText(os.Stdout, diff)
+	}
+	return nil
+}
+
+// writeDiffText writes the changes by unit and pass, marking added issues
+// with "+", removed ones with "-" and severity changes with "~".
+func writeDiffText(w io.Writer, diff *report.Diff) {
+	for _, unit := range diff.Units {
+		switch unit.Status {
+		case report.UnitAdded:
+			fmt.Fprintf(w, "%s (new unit)\n", unit.Unit)
+		case report.UnitRemoved:
+			fmt.Fprintf(w, "%s (removed unit)\n", unit.Unit)
+		default:
+			fmt.Fprintln(w, unit.Unit)
+		}
+
+		for _, pass := range unit.Passes {
+			fmt.Fprintf(w, "  %s\n", pass.Pass)
+			for _, issue := range pass.Added {
+				fmt.Fprintf(w, "    + [%s] %s:%d: %s\n", issue.Severity, issue.Position.Filename, issue.Position.Line, issue.Message)
+			}
+			for _, issue := range pass.Removed {
+				fmt.Fprintf(w, "    - [%s] %s:%d: %s\n", issue.Severity, issue.Position.Filename, issue.Position.Line, issue.Message)
+			}
+			for _, change := range pass.Severity {
+				issue := change.New
+				fmt.Fprintf(w, "    ~ [%s -> %s] %s:%d: %s\n", change.Old.Severity, issue.Severity, issue.Position.Filename, issue.Position.Line, issue.Message)
+			}
+		}
+
+		if unit.Summary != nil {
+			old, newer := unit.Summary.Old, unit.Summary.New
+			fmt.Fprintln(w, "  summary")
+			field := func(name, old, newer string) {
+				if old != newer {
+					fmt.Fprintf(w, "    %s:\n      - %s\n      + %s\n", name, old, newer)
+				}
+			}
+			field("purpose", old.Purpose, newer.Purpose)
+			field("behavior", old.Behavior, newer.Behavior)
+			field("invariants", strings.Join(old.Invariants, "; "), strings.Join(newer.Invariants, "; "))
+			field("security", strings.Join(old.Security, "; "), strings.Join(newer.Security, "; "))
+		}
+	}
+
+	if len(diff.Units) > 0 {
+		fmt.Fprintln(w)
+	}
+	s := diff.Summary
+	fmt.Fprintf(w, "%d added, %d removed, %d severity changed, %d unchanged, %d summaries changed\n",
+		s.Added, s.Removed, s.Severity, s.Unchanged, s.Summaries)
+}
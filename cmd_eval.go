@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/zeebo/clingy"
+
+	"github.com/loov/dreamlint/analyze"
+	"github.com/loov/dreamlint/config"
+	"github.com/loov/dreamlint/eval"
+)
+
+type cmdEval struct {
+	configPaths   []string
+	inlineConfigs []string
+	format        string
+	passes        []string
+	models        []string
+	verbose       bool
+	promptsDir    string
+	corpus        []string
+}
+
+func (c *cmdEval) Setup(params clingy.Parameters) {
+	c.configPaths = params.Flag("config", "path to config file",
+		[]string{"dreamlint.cue"},
+		clingy.Repeated,
+	).([]string)
+
+	c.inlineConfigs = params.Flag("c", "inline CUE config",
+		[]string{},
+		clingy.Repeated,
+	).([]string)
+
+	c.format = params.Flag("format", "output format: text or json", "text").(string)
+
+	c.passes = params.Flag("pass", "analysis pass to evaluate, all enabled passes if not set",
+		[]string{},
+		clingy.Repeated,
+	).([]string)
+
+	c.models = params.Flag("model", "model profile to evaluate every pass with, the configured models if not set",
+		[]string{},
+		clingy.Repeated,
+	).([]string)
+
+	c.verbose = params.Flag("verbose", "list the missed and unexpected issues", false,
+		clingy.Transform(strconv.ParseBool), clingy.Boolean,
+	).(bool)
+
+	c.promptsDir = params.Flag("prompts", "directory to load prompts from", "").(string)
+
+	c.corpus = params.Arg("corpus", "txtar files or directories of txtar files with expected issues",
+		clingy.Repeated,
+	).([]string)
+}
+
+func (c *cmdEval) Execute(ctx context.Context) error {
+	if c.format != "text" && c.format != "json" {
+		return fmt.Errorf("unknown format %q", c.format)
+	}
+
+	cfg, err := config.LoadConfig(c.configPaths, c.inlineConfigs)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if err := selectPasses(cfg, c.passes); err != nil {
+		return err
+	}
+	// Fixes are not scored, and examples from triage decisions of the
+	// project would leak into the prompts of the cases. Cached summaries
+	// do not depend on the model, so every model has to summarize anew.
+	cfg.Fix.Enabled = false
+	cfg.Examples.Max = 0
+	cfg.Cache.Enabled = false
+
+	cases, err := eval.LoadCorpus(c.corpus...)
+	if err != nil {
+		return fmt.Errorf("load corpus: %w", err)
+	}
+	if len(cases) == 0 {
+		return fmt.Errorf("no cases found in %s", strings.Join(c.corpus, ", "))
+	}
+	passes := evalPasses(cfg)
+	if err := checkCorpus(cfg, cases); err != nil {
+		return err
+	}
+
+	models := c.models
+	if len(models) == 0 {
+		models = []string{""}
+	}
+
+	var results []eval.Result
+	for _, model := range models {
+		if model != "" {
+			if err := useModel(cfg, model); err != nil {
+				return err
+			}
+		}
+		for i, kase := range cases {
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s", i+1, len(cases), kase.Name)
+			if model != "" {
+				fmt.Fprintf(os.Stderr, " with %s", model)
+			}
+			fmt.Fprintln(os.Stderr)
+
+			caseResults, err := c.evalCase(ctx, cfg, passes, kase)
+			if err != nil {
+				return fmt.Errorf("%s: %w", kase.Name, err)
+			}
+			results = append(results, caseResults...)
+		}
+	}
+
+	scores := eval.Scores(results)
+	if c.format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(evalOutput{Scores: scores, Results: results})
+	}
+	if c.verbose {
+		writeEvalResults(os.Stdout, results)
+	}
+	return writeEvalScores(os.Stdout, scores)
+}
+
+// evalOutput is the JSON output of the eval command.
+type evalOutput struct {
+	Scores  []eval.Score  `json:"scores"`
+	Results []eval.Result `json:"results"`
+}
+
+// evalPasses returns the enabled analysis passes of cfg, leaving out the
+// summary pass, which does not report issues.
+func evalPasses(cfg *config.Config) []config.AnalysisPass {
+	var passes []config.AnalysisPass
+	for _, pass := range cfg.Analyse {
+		if pass.Enabled && pass.Name != "summary" {
+			passes = append(passes, pass)
+		}
+	}
+	return passes
+}
+
+// checkCorpus checks that the expected issues of cases are in categories of
+// the configured passes, to catch misspelled categories.
+func checkCorpus(cfg *config.Config, cases []*eval.Case) error {
+	known := make(map[string]bool)
+	for _, pass := range cfg.AllPasses() {
+		known[pass.Name] = true
+	}
+	for _, kase := range cases {
+		for _, expectation := range kase.Expected {
+			if !known[expectation.Category] {
+				return fmt.Errorf("%s: %s:%d: unknown pass %q", kase.Name, expectation.File, expectation.Line, expectation.Category)
+			}
+		}
+		for _, pass := range kase.Passes {
+			if !known[pass] {
+				return fmt.Errorf("%s: unknown pass %q", kase.Name, pass)
+			}
+		}
+	}
+	return nil
+}
+
+// useModel makes every pass of cfg, including the passes of overrides, use
+// the model profile name.
+func useModel(cfg *config.Config, name string) error {
+	if _, ok := cfg.Models[name]; !ok {
+		return fmt.Errorf("unknown model %q, models are defined in models", name)
+	}
+	use := func(passes []config.AnalysisPass) {
+		for i := range passes {
+			passes[i].LLM = nil
+			passes[i].Models = []string{name}
+		}
+	}
+	use(cfg.Analyse)
+	for i := range cfg.Overrides {
+		override := &cfg.Overrides[i]
+		use(override.Analyse)
+		if override.LLM != nil || len(override.Models) > 0 {
+			override.LLM = nil
+			override.Models = []string{name}
+		}
+	}
+	return nil
+}
+
+// evalCase analyzes the units of kase and matches the reported issues to the
+// expected ones, returning a result for every scored pass.
+func (c *cmdEval) evalCase(ctx context.Context, cfg *config.Config, passes []config.AnalysisPass, kase *eval.Case) ([]eval.Result, error) {
+	dir, err := os.MkdirTemp("", "dreamlint-eval-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	// Issue positions are resolved, e.g. /tmp may be a symlink
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		return nil, err
+	}
+	if err := kase.Extract(dir); err != nil {
+		return nil, err
+	}
+
+	l, err := loadUnits(io.Discard, cfg, false, dir, []string{"./..."})
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := loadPipeline(cfg, nil, l.externalFuncs, c.promptsDir)
+	if err != nil {
+		return nil, err
+	}
+	// Overrides match paths within the case
+	pipeline.SetWorkDir(dir)
+
+	findings := make(map[string][]eval.Finding)
+	summaries := make(map[string]*analyze.SummaryResponse)
+	for _, unit := range l.units {
+		unitReport, err := pipeline.Analyze(ctx, unit, summaries)
+		if err != nil {
+			return nil, fmt.Errorf("analyze %s: %w", unit.ID, err)
+		}
+		if summary := pipeline.GetSummary(unit.ID); summary != nil {
+			summaries[unit.ID] = summary
+		}
+		for _, issue := range unitReport.Issues {
+			file, err := filepath.Rel(dir, issue.Position.Filename)
+			if err != nil {
+				file = issue.Position.Filename
+			}
+			findings[issue.Category] = append(findings[issue.Category], eval.Finding{
+				File:     filepath.ToSlash(file),
+				Line:     issue.Position.Line,
+				EndLine:  issue.EndLine,
+				Category: issue.Category,
+				Message:  issue.Message,
+			})
+		}
+	}
+
+	var results []eval.Result
+	for _, pass := range passes {
+		if !kase.Scores(pass.Name) {
+			continue
+		}
+		var expected []eval.Expectation
+		for _, expectation := range kase.Expected {
+			if expectation.Category == pass.Name {
+				expected = append(expected, expectation)
+			}
+		}
+		found, missed, unexpected := eval.Match(expected, findings[pass.Name])
+		results = append(results, eval.Result{
+			Case:       kase.Name,
+			Pass:       pass.Name,
+			Model:      passModel(cfg, pass),
+			Found:      found,
+			Missed:     missed,
+			Unexpected: unexpected,
+		})
+	}
+	return results, nil
+}
+
+// passModel describes the models of pass, the fallback models separated by commas.
+func passModel(cfg *config.Config, pass config.AnalysisPass) string {
+	var names []string
+	for _, llmCfg := range cfg.PassLLMs(pass) {
+		names = append(names, llmCfg.Model)
+	}
+	return strings.Join(names, ",")
+}
+
+// writeEvalResults lists the missed and unexpected issues of results.
+func writeEvalResults(w io.Writer, results []eval.Result) {
+	for _, result := range results {
+		for _, expectation := range result.Missed {
+			fmt.Fprintf(w, "%s: %s:%d: missed %s issue (%s)", result.Case, expectation.File, expectation.Line, expectation.Category, result.Model)
+			if expectation.Message != "" {
+				fmt.Fprintf(w, ": %s", expectation.Message)
+			}
+			fmt.Fprintln(w)
+		}
+		for _, finding := range result.Unexpected {
+			fmt.Fprintf(w, "%s: %s:%d: unexpected %s issue (%s): %s\n", result.Case, finding.File, finding.Line, finding.Category, result.Model, finding.Message)
+		}
+	}
+	if len(results) > 0 {
+		fmt.Fprintln(w)
+	}
+}
+
+// writeEvalScores writes scores as a table.
+func writeEvalScores(w io.Writer, scores []eval.Score) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PASS\tMODEL\tCASES\tTP\tFP\tFN\tPRECISION\tRECALL\tF1")
+	for _, s := range scores {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%.2f\t%.2f\t%.2f\n",
+			s.Pass, s.Model, s.Cases, s.TruePositives, s.FalsePositives, s.FalseNegatives,
+			s.Precision, s.Recall, s.F1)
+	}
+	return tw.Flush()
+}
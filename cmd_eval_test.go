@@ -0,0 +1,48 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/loov/dreamlint/config"
+)
+
+func TestUseModel(t *testing.T) {
+	cfg := &config.Config{
+		Models: map[string]config.LLMConfig{
+			"small": {Model: "small-model"},
+			"big":   {Model: "big-model"},
+		},
+		Analyse: []config.AnalysisPass{
+			{Name: "summary", Enabled: true},
+			{Name: "security", Enabled: true, LLM: &config.LLMConfig{Model: "pass-model"}},
+			{Name: "correctness", Enabled: true, Models: []string{"small", "big"}},
+		},
+		Overrides: []config.Override{
+			{Match: []string{"gen/..."}, Models: []string{"small"}},
+			{Match: []string{"api/..."}, Analyse: []config.AnalysisPass{{Name: "security", Enabled: true}}},
+		},
+	}
+
+	if err := useModel(cfg, "big"); err != nil {
+		t.Fatalf("useModel: %v", err)
+	}
+	for _, pass := range cfg.AllPasses() {
+		if got := passModel(cfg, pass); got != "big-model" {
+			t.Errorf("pass %s uses %q, want big-model", pass.Name, got)
+		}
+	}
+	if !slices.Equal(cfg.Overrides[0].Models, []string{"big"}) {
+		t.Errorf("override models = %q, want [big]", cfg.Overrides[0].Models)
+	}
+	if cfg.Overrides[1].LLM != nil || cfg.Overrides[1].Models != nil {
+		t.Errorf("override without a model got one: %+v", cfg.Overrides[1])
+	}
+	if got := evalPasses(cfg); len(got) != 2 || got[0].Name != "security" || got[1].Name != "correctness" {
+		t.Errorf("evaluated passes = %+v", got)
+	}
+
+	if err := useModel(cfg, "missing"); err == nil {
+		t.Error("expected an error for an unknown model")
+	}
+}
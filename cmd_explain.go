@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zeebo/clingy"
+
+	"github.com/loov/dreamlint/analyze"
+	"github.com/loov/dreamlint/config"
+	"github.com/loov/dreamlint/extract"
+	"github.com/loov/dreamlint/report"
+)
+
+type cmdExplain struct {
+	configPaths   []string
+	inlineConfigs []string
+	format        string
+	passes        []string
+	reportPath    string
+	allowErrors   bool
+	promptsDir    string
+	target        string
+	patterns      []string
+}
+
+func (c *cmdExplain) Setup(params clingy.Parameters) {
+	c.configPaths = params.Flag("config", "path to config file",
+		[]string{"dreamlint.cue"},
+		clingy.Repeated,
+	).([]string)
+
+	c.inlineConfigs = params.Flag("c", "inline CUE config",
+		[]string{},
+		clingy.Repeated,
+	).([]string)
+
+	c.format = params.Flag("format", "output format: text or json", "text").(string)
+
+	c.passes = params.Flag("pass", "analysis pass to run, all enabled passes if not set",
+		[]string{},
+		clingy.Repeated,
+	).([]string)
+
+	c.reportPath = params.Flag("report", "JSON report to take callee summaries from", "").(string)
+
+	c.allowErrors = params.Flag("allow-errors", "skip packages with errors instead of failing", false,
+		clingy.Transform(strconv.ParseBool), clingy.Boolean,
+	).(bool)
+
+	c.promptsDir = params.Flag("prompts", "directory to load prompts from", "").(string)
+
+	c.target = params.Arg("function", "function or unit ID, or file.go:line").(string)
+
+	c.patterns = params.Arg("patterns", "packages to analyze",
+		clingy.Optional,
+		clingy.Repeated,
+	).([]string)
+}
+
+func (c *cmdExplain) Execute(ctx context.Context) error {
+	patterns := c.patterns
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	if c.format != "text" && c.format != "json" {
+		return fmt.Errorf("unknown format %q", c.format)
+	}
+
+	cfg, err := config.LoadConfig(c.configPaths, c.inlineConfigs)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if err := selectPasses(cfg, c.passes); err != nil {
+		return err
+	}
+
+	// Progress goes to stderr so the explanation can be piped
+	l, err := loadUnits(os.Stderr, cfg, c.allowErrors, ".", patterns)
+	if err != nil {
+		return err
+	}
+
+	target, err := findUnit(l, c.target)
+	if err != nil {
+		return err
+	}
+
+	pipeline, err := loadPipeline(cfg, nil, l.externalFuncs, c.promptsDir)
+	if err != nil {
+		return err
+	}
+	pipeline.SetFixChecker(l.pkgs.CheckFile)
+	pipeline.OnProgress(func(event analyze.ProgressEvent) {
+		if event.Fallback != nil {
+			fmt.Fprintf(os.Stderr, "%s failed: %v, trying %s\n", event.Fallback.Model, event.Fallback.Err, event.Fallback.Next)
+		}
+		if event.FixError != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", event.FixError)
+		}
+	})
+
+	calleeSummaries := make(map[string]*analyze.SummaryResponse)
+	if c.reportPath != "" {
+		rpt, err := report.ReadJSONFile(c.reportPath)
+		if err != nil {
+			return fmt.Errorf("read report: %w", err)
+		}
+		calleeSummaries = reportSummaries(rpt)
+	}
+
+	// Summarize the callees that are not in the report, the cache is used when possible
+	closure := extract.CalleeClosure(l.units, target)
+	for i, unit := range closure[:len(closure)-1] {
+		if _, ok := calleeSummaries[unit.ID]; ok {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "[%d/%d] Summarizing %s\n", i+1, len(closure)-1, unit.ID)
+		summary, err := pipeline.Summarize(ctx, unit, calleeSummaries)
+		if err != nil {
+			return err
+		}
+		calleeSummaries[unit.ID] = summary
+	}
+
+	fmt.Fprintf(os.Stderr, "Analyzing %s\n", target.ID)
+	unitReport, err := pipeline.Analyze(ctx, target, calleeSummaries)
+	if err != nil {
+		return fmt.Errorf("analyze %s: %w", target.ID, err)
+	}
+
+	if c.format == "json" {
+		// Paths are relative to the module root, like in reports
+		paths, err := reportPaths(l.pkgs.ModuleRoot(), "")
+		if err != nil {
+			return err
+		}
+		paths.Unit(unitReport)
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(explainResult{Unit: target.ID, UnitReport: unitReport})
+	}
+	return writeExplainText(os.Stdout, target.ID, unitReport)
+}
+
+// explainResult is the JSON output of the explain command.
+type explainResult struct {
+	Unit string `json:"unit"`
+	*report.UnitReport
+}
+
+// selectPasses enables only the named analysis passes in cfg, including the
+// passes of overrides. The summary pass is always kept, since analysis passes
+// depend on it.
+func selectPasses(cfg *config.Config, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	for _, name := range names {
+		if !slices.ContainsFunc(cfg.AllPasses(), func(pass config.AnalysisPass) bool { return pass.Name == name }) {
+			return fmt.Errorf("unknown analysis pass %q", name)
+		}
+	}
+	enable := func(passes []config.AnalysisPass) {
+		for i := range passes {
+			pass := &passes[i]
+			if pass.Name != "summary" {
+				pass.Enabled = slices.Contains(names, pass.Name)
+			}
+		}
+	}
+	enable(cfg.Analyse)
+	for i := range cfg.Overrides {
+		enable(cfg.Overrides[i].Analyse)
+	}
+	return nil
+}
+
+// locationRx matches a file.go:line location.
+var locationRx = regexp.MustCompile(`^(.+\.go):(\d+)$`)
+
+// findUnit returns the unit containing the function at target,
+// which is either a function or unit ID, or a file.go:line location.
+func findUnit(l *loaded, target string) (*extract.AnalysisUnit, error) {
+	if m := locationRx.FindStringSubmatch(target); m != nil {
+		line, err := strconv.Atoi(m[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid line in %q: %w", target, err)
+		}
+		fn := extract.FunctionAt(l.funcs, m[1], line)
+		if fn == nil {
+			return nil, fmt.Errorf("no function found at %s", target)
+		}
+		target = fn.ID()
+	}
+
+	unit := extract.UnitOf(l.units, target)
+	if unit == nil {
+		return nil, fmt.Errorf("function or unit %q not found", target)
+	}
+	return unit, nil
+}
+
+// writeExplainText writes the summary and issues of a unit in a readable form.
+func writeExplainText(w io.Writer, unitID string, unitReport *report.UnitReport) error {
+	fmt.Fprintf(w, "%s\n\n", unitID)
+	for _, fn := range unitReport.Functions {
+		fmt.Fprintf(w, "  %s at %s\n", fn.ID, fn.Position)
+	}
+
+	summary := unitReport.Summary
+	fmt.Fprintf(w, "\nPurpose: %s\n", summary.Purpose)
+	fmt.Fprintf(w, "Behavior: %s\n", summary.Behavior)
+	writeList(w, "Invariants", summary.Invariants)
+	writeList(w, "Security", summary.Security)
+
+	if len(unitReport.Issues) == 0 {
+		_, err := fmt.Fprintln(w, "\nNo issues found")
+		return err
+	}
+
+	fmt.Fprintf(w, "\nFound %d issue(s):\n", len(unitReport.Issues))
+	for _, issue := range unitReport.Issues {
+		fmt.Fprintf(w, "\n%s: [%s] %s\n", issue.Position, issue.Severity, issue.Category)
+		fmt.Fprintf(w, "  %s\n", issue.Message)
+		if issue.Suggestion != "" {
+			fmt.Fprintf(w, "  Suggestion: %s\n", issue.Suggestion)
+		}
+		if issue.Fix != nil {
+			fmt.Fprintf(w, "  Fix:\n")
+			for _, line := range strings.SplitAfter(strings.TrimSuffix(issue.Fix.Diff, "\n"), "\n") {
+				fmt.Fprintf(w, "    %s", line)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	return nil
+}
+
+// writeList writes a titled bullet list, or nothing when items is empty.
+func writeList(w io.Writer, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, item := range items {
+		fmt.Fprintf(w, "  - %s\n", item)
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zeebo/clingy"
+
+	"github.com/loov/dreamlint/analyze"
+	"github.com/loov/dreamlint/config"
+	"github.com/loov/dreamlint/extract"
+	"github.com/loov/dreamlint/report"
+)
+
+type cmdFix struct {
+	configPaths   []string
+	inlineConfigs []string
+	categories    []string
+	severity      string
+	units         []string
+	interactive   bool
+	dryRun        bool
+	generate      bool
+	check         bool
+	allowErrors   bool
+	promptsDir    string
+	reportPath    string
+}
+
+func (c *cmdFix) Setup(params clingy.Parameters) {
+	c.configPaths = params.Flag("config", "path to config file",
+		[]string{"dreamlint.cue"},
+		clingy.Repeated,
+	).([]string)
+
+	c.inlineConfigs = params.Flag("c", "inline CUE config",
+		[]string{},
+		clingy.Repeated,
+	).([]string)
+
+	c.categories = params.Flag("category", "fix only issues of the category, all categories if not set",
+		[]string{},
+		clingy.Repeated,
+	).([]string)
+
+	c.severity = params.Flag("severity", "fix only issues of at least the severity", "").(string)
+
+	c.units = params.Flag("unit", "fix only issues of units matching the pattern, all units if not set",
+		[]string{},
+		clingy.Repeated,
+	).([]string)
+
+	c.interactive = params.Flag("interactive", "ask before applying each fix", false,
+		clingy.Transform(strconv.ParseBool), clingy.Boolean,
+	).(bool)
+
+	c.dryRun = params.Flag("dry-run", "print the fixes without applying them", false,
+		clingy.Transform(strconv.ParseBool), clingy.Boolean,
+	).(bool)
+
+	c.generate = params.Flag("generate", "ask the LLM for fixes of issues without one", true,
+		clingy.Transform(strconv.ParseBool), clingy.Boolean,
+	).(bool)
+
+	c.check = params.Flag("check", "run go build and go vet on the changed packages", false,
+		clingy.Transform(strconv.ParseBool), clingy.Boolean,
+	).(bool)
+
+	c.allowErrors = params.Flag("allow-errors", "skip packages with errors instead of failing", false,
+		clingy.Transform(strconv.ParseBool), clingy.Boolean,
+	).(bool)
+
+	c.promptsDir = params.Flag("prompts", "directory to load prompts from", "").(string)
+
+	c.reportPath = params.Arg("report", "JSON report to fix the issues of").(string)
+}
+
+func (c *cmdFix) Execute(ctx context.Context) error {
+	cfg, err := config.LoadConfig(c.configPaths, c.inlineConfigs)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	// The fix prompt is only loaded when fixes are enabled
+	cfg.Fix.Enabled = true
+
+	rpt, err := report.ReadJSONFile(c.reportPath)
+	if err != nil {
+		return fmt.Errorf("read report: %w", err)
+	}
+
+	selector, err := newIssueSelector(rpt, c.categories, c.severity, c.units)
+	if err != nil {
+		return err
+	}
+
+	targets := selector.Select(rpt)
+	targets = checkFileHashes(os.Stderr, targets)
+	if len(targets) == 0 {
+		fmt.Fprintln(os.Stderr, "No issues to fix")
+		return nil
+	}
+
+	// The packages of the files are needed to generate and type-check fixes
+	var patterns []string
+	for _, filename := range targetFiles(targets) {
+		patterns = append(patterns, "file="+filename)
+	}
+	l, err := loadUnits(os.Stderr, cfg, c.allowErrors, ".", patterns)
+	if err != nil {
+		return err
+	}
+
+	if c.generate {
+		if err := c.generateFixes(ctx, cfg, l, rpt, targets); err != nil {
+			return err
+		}
+	}
+
+	targets = slices.DeleteFunc(targets, func(target *selectedIssue) bool { return target.issue.Fix == nil })
+	if c.interactive && !c.dryRun {
+		targets, err = chooseFixes(os.Stdin, os.Stdout, targets)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, target := range targets {
+			writeFix(os.Stdout, target)
+		}
+	}
+	if c.dryRun || len(targets) == 0 {
+		return nil
+	}
+
+	var pkgPaths []string
+	for _, filename := range targetFiles(targets) {
+		fixes := make([]*report.Fix, 0, len(targets))
+		for _, target := range targets {
+			if target.filename == filename {
+				fixes = append(fixes, target.issue.Fix)
+			}
+		}
+		applied, err := applyFileFixes(filename, fixes, l.pkgs.CheckFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Not fixing %s: %v\n", filename, err)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Applied %d of %d fix(es) to %s\n", applied, len(fixes), filename)
+		if pkgPath := l.pkgs.PackagePath(filename); pkgPath != "" && !slices.Contains(pkgPaths, pkgPath) {
+			pkgPaths = append(pkgPaths, pkgPath)
+		}
+	}
+
+	if c.check && len(pkgPaths) > 0 {
+		for _, tool := range []string{"build", "vet"} {
+			fmt.Fprintf(os.Stderr, "Running go %s...\n", tool)
+			cmd := exec.CommandContext(ctx, "go", append([]string{tool}, pkgPaths...)...)
+			cmd.Stdout = os.Stderr
+			cmd.Stderr = os.Stderr
+			if err := cmd.Run(); err != nil {
+				return fmt.Errorf("go %s: %w", tool, err)
+			}
+		}
+	}
+	return nil
+}
+
+// generateFixes asks the LLM for fixes of the targets without one.
+func (c *cmdFix) generateFixes(ctx context.Context, cfg *config.Config, l *loaded, rpt *report.Report, targets []*selectedIssue) error {
+	missing := slices.ContainsFunc(targets, func(target *selectedIssue) bool { return target.issue.Fix == nil })
+	if !missing {
+		return nil
+	}
+
+	pipeline, err := loadPipeline(cfg, nil, l.externalFuncs, c.promptsDir)
+	if err != nil {
+		return err
+	}
+	pipeline.SetFixChecker(l.pkgs.CheckFile)
+	pipeline.OnProgress(func(event analyze.ProgressEvent) {
+		if event.Fallback != nil {
+			fmt.Fprintf(os.Stderr, "%s failed: %v, trying %s\n", event.Fallback.Model, event.Fallback.Err, event.Fallback.Next)
+		}
+	})
+
+	summaries := reportSummaries(rpt)
+	for _, target := range targets {
+		if target.issue.Fix != nil {
+			continue
+		}
+		unit := extract.UnitOf(l.units, target.unitID)
+		if unit == nil {
+			fmt.Fprintf(os.Stderr, "Not fixing %s: unit %s not found\n", target.position(), target.unitID)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Generating fix for %s\n", target.position())
+		fix, err := pipeline.Fix(ctx, unit, summaries, *target.issue)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Fprintf(os.Stderr, "Not fixing %s: %v\n", target.position(), err)
+			continue
+		}
+		target.issue.Fix = fix
+	}
+	return nil
+}
+
+// checkFileHashes returns the targets whose files did not change since the
+// report was generated, writing the reason for dropping the others to log.
+// Files that cannot be read, e.g. because they were removed, count as changed.
+func checkFileHashes(log io.Writer, targets []*selectedIssue) []*selectedIssue {
+	changed := make(map[string]error)
+	for _, filename := range targetFiles(targets) {
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				err = errors.New("the file was removed since the report was generated")
+			}
+			changed[filename] = err
+			continue
+		}
+		hash := extract.FileHash(content)
+		for _, target := range targets {
+			if target.filename != filename || target.function.FileHash == hash {
+				continue
+			}
+			if target.function.FileHash == "" {
+				changed[filename] = errors.New("the report does not include file hashes, rerun the analysis")
+			} else {
+				changed[filename] = errors.New("the file changed since the report was generated")
+			}
+			break
+		}
+	}
+
+	for _, filename := range targetFiles(targets) {
+		if err, ok := changed[filename]; ok {
+			fmt.Fprintf(log, "Not fixing %s: %v\n", filename, err)
+		}
+	}
+	return slices.DeleteFunc(targets, func(target *selectedIssue) bool {
+		return changed[target.filename] != nil
+	})
+}
+
+// writeFix writes the issue and the diff of its fix.
+func writeFix(w io.Writer, target *selectedIssue) {
+	issue := target.issue
+	fmt.Fprintf(w, "%s: [%s] %s: %s\n", target.position(), issue.Severity, issue.Category, issue.Message)
+	fmt.Fprintln(w, strings.TrimSuffix(issue.Fix.Diff, "\n"))
+	fmt.Fprintln(w)
+}
+
+// chooseFixes asks for each target whether its fix should be applied.
+func chooseFixes(in io.Reader, out io.Writer, targets []*selectedIssue) ([]*selectedIssue, error) {
+	scanner := bufio.NewScanner(in)
+	var chosen []*selectedIssue
+	for i, target := range targets {
+		writeFix(out, target)
+		for {
+			fmt.Fprint(out, "Apply fix? [y]es, [n]o, [a]ll remaining, [q]uit: ")
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return nil, err
+				}
+				// Stop at the end of input, as with quit
+				fmt.Fprintln(out)
+				return chosen, nil
+			}
+			switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+			case "y", "yes":
+				chosen = append(chosen, target)
+			case "n", "no":
+			case "a", "all":
+				return append(chosen, targets[i:]...), nil
+			case "q", "quit":
+				return chosen, nil
+			default:
+				continue
+			}
+			break
+		}
+		fmt.Fprintln(out)
+	}
+	return chosen, nil
+}
+
+// applyFileFixes applies fixes to filename, formats the result and checks
+// it with check. It returns the number of applied fixes.
+func applyFileFixes(filename string, fixes []*report.Fix, check func(filename string, src []byte) error) (int, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return 0, err
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return 0, err
+	}
+
+	src, applied, err := applyFixes(content, fixes)
+	if err != nil {
+		return 0, err
+	}
+	src, err = format.Source(src)
+	if err != nil {
+		return 0, fmt.Errorf("fixed file does not parse: %w", err)
+	}
+	if err := check(filename, src); err != nil {
+		return 0, fmt.Errorf("fixed file does not type-check: %w", err)
+	}
+	if err := os.WriteFile(filename, src, info.Mode().Perm()); err != nil {
+		return 0, err
+	}
+	return applied, nil
+}
+
+// applyFixes replaces the regions of fixes in content. A fix overlapping an
+// earlier one, e.g. two fixes of the same function, is not applied.
+func applyFixes(content []byte, fixes []*report.Fix) (src []byte, applied int, err error) {
+	type edit struct {
+		start, end  int
+		replacement string
+	}
+	lines := lineOffsets(content)
+	offset := func(line, column int) (int, error) {
+		if line < 1 || line > len(lines) || column < 1 {
+			return 0, fmt.Errorf("position %d:%d outside of the file", line, column)
+		}
+		off := lines[line-1] + column - 1
+		if off > len(content) {
+			return 0, fmt.Errorf("position %d:%d outside of the file", line, column)
+		}
+		return off, nil
+	}
+
+	var edits []edit
+	for _, fix := range fixes {
+		start, err := offset(fix.StartLine, fix.StartColumn)
+		if err != nil {
+			return nil, 0, err
+		}
+		end, err := offset(fix.EndLine, fix.EndColumn)
+		if err != nil {
+			return nil, 0, err
+		}
+		if end < start {
+			return nil, 0, fmt.Errorf("fix ends at %d:%d before it starts", fix.EndLine, fix.EndColumn)
+		}
+		if slices.ContainsFunc(edits, func(e edit) bool { return start < e.end && e.start < end }) {
+			continue
+		}
+		edits = append(edits, edit{start: start, end: end, replacement: fix.Replacement})
+	}
+	slices.SortFunc(edits, func(a, b edit) int { return cmp.Compare(a.start, b.start) })
+
+	var buf bytes.Buffer
+	last := 0
+	for _, e := range edits {
+		buf.Write(content[last:e.start])
+		buf.WriteString(e.replacement)
+		last = e.end
+	}
+	buf.Write(content[last:])
+	return buf.Bytes(), len(edits), nil
+}
+
+// lineOffsets returns the offsets of the starts of lines in content.
+func lineOffsets(content []byte) []int {
+	offsets := []int{0}
+	for i, b := range content {
+		if b == '\n' {
+			offsets = append(offsets, i+1)
+		}
+	}
+	return offsets
+}
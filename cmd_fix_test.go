@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/loov/dreamlint/extract"
+	"github.com/loov/dreamlint/report"
+)
+
+func TestApplyFixes(t *testing.T) {
+	content := []byte("package p\n\nfunc A() int { return 1 }\n\nfunc B() int {\n\treturn 2\n}\n")
+	fixes := []*report.Fix{
+		{StartLine: 5, StartColumn: 1, EndLine: 7, EndColumn: 2, Replacement: "func B() int { return 20 }"},
+		{StartLine: 3, StartColumn: 1, EndLine: 3, EndColumn: 26, Replacement: "func A() int { return 10 }"},
+		// Overlaps the fix of B
+		{StartLine: 5, StartColumn: 1, EndLine: 7, EndColumn: 2, Replacement: "func B() int { return 30 }"},
+	}
+
+	src, applied, err := applyFixes(content, fixes)
+	if err != nil {
+		t.Fatalf("applyFixes: %v", err)
+	}
+	if applied != 2 {
+		t.Errorf("applied = %d, want 2", applied)
+	}
+	want := "package p\n\nfunc A() int { return 10 }\n\nfunc B() int { return 20 }\n"
+	if string(src) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", src, want)
+	}
+
+	if _, _, err := applyFixes(content, []*report.Fix{{StartLine: 9, StartColumn: 1, EndLine: 9, EndColumn: 1}}); err == nil {
+		t.Error("fix outside of the file was applied")
+	}
+}
+
+func TestApplyFileFixes(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "a.go")
+	content := "package p\n\nfunc A() int { return 1 }\n"
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fix := &report.Fix{StartLine: 3, StartColumn: 1, EndLine: 3, EndColumn: 26, Replacement: "func A() int {\nreturn   2\n}"}
+
+	rejected := errors.New("rejected")
+	if _, err := applyFileFixes(filename, []*report.Fix{fix}, func(string, []byte) error { return rejected }); !errors.Is(err, rejected) {
+		t.Errorf("applyFileFixes error = %v, want the check error", err)
+	}
+	if data, _ := os.ReadFile(filename); string(data) != content {
+		t.Errorf("file changed after a failed check:\n%s", data)
+	}
+
+	if _, err := applyFileFixes(filename, []*report.Fix{fix}, func(string, []byte) error { return nil }); err != nil {
+		t.Fatalf("applyFileFixes: %v", err)
+	}
+	// The result is formatted
+	if data, _ := os.ReadFile(filename); string(data) != "package p\n\nfunc A() int {\n\treturn 2\n}\n" {
+		t.Errorf("got:\n%s", data)
+	}
+}
+
+func TestCheckFileHashes(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("package p\n")
+	unchanged := filepath.Join(dir, "unchanged.go")
+	changed := filepath.Join(dir, "changed.go")
+	for _, filename := range []string{unchanged, changed} {
+		if err := os.WriteFile(filename, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	target := func(filename, hash string) *selectedIssue {
+		return &selectedIssue{
+			issue:    &report.Issue{},
+			function: report.FunctionInfo{FileHash: hash},
+			filename: filename,
+		}
+	}
+
+	removed := filepath.Join(dir, "removed.go")
+
+	var log bytes.Buffer
+	targets := checkFileHashes(&log, []*selectedIssue{
+		target(unchanged, extract.FileHash(content)),
+		target(changed, extract.FileHash([]byte("package old\n"))),
+		target(removed, extract.FileHash(content)),
+	})
+	if len(targets) != 1 || targets[0].filename != unchanged {
+		t.Errorf("got %d targets, want only %s", len(targets), unchanged)
+	}
+	if !strings.Contains(log.String(), changed+": the file changed") {
+		t.Errorf("log does not mention the changed file:\n%s", log.String())
+	}
+	if !strings.Contains(log.String(), removed+": the file was removed") {
+		t.Errorf("log does not mention the removed file:\n%s", log.String())
+	}
+}
+
+func TestChooseFixes(t *testing.T) {
+	var targets []*selectedIssue
+	for _, message := range []string{"a", "b", "c", "d"} {
+		targets = append(targets, &selectedIssue{issue: &report.Issue{
+			Message: message,
+			Fix:     &report.Fix{Diff: "--- a.go\n+++ a.go\n"},
+		}})
+	}
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"y\nn\ny\nn\n", []string{"a", "c"}},
+		{"n\nwhat\ny\nq\n", []string{"b"}},
+		{"n\na\n", []string{"b", "c", "d"}},
+		{"y\n", []string{"a"}},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		chosen, err := chooseFixes(strings.NewReader(test.input), &out, targets)
+		if err != nil {
+			t.Fatalf("chooseFixes: %v", err)
+		}
+		var got []string
+		for _, target := range chosen {
+			got = append(got, target.issue.Message)
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("input %q: got %v, want %v", test.input, got, test.want)
+		}
+	}
+}
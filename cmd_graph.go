@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/zeebo/clingy"
+
+	"github.com/loov/dreamlint/config"
+	"github.com/loov/dreamlint/extract"
+	"github.com/loov/dreamlint/graph"
+	"github.com/loov/dreamlint/report"
+)
+
+type cmdGraph struct {
+	configPaths   []string
+	inlineConfigs []string
+	kind          string
+	format        string
+	output        string
+	reportPath    string
+	pkg           string
+	focus         string
+	depth         int
+	allowErrors   bool
+	patterns      []string
+}
+
+func (c *cmdGraph) Setup(params clingy.Parameters) {
+	c.configPaths = params.Flag("config", "path to config file",
+		[]string{"dreamlint.cue"},
+		clingy.Repeated,
+	).([]string)
+
+	c.inlineConfigs = params.Flag("c", "inline CUE config",
+		[]string{},
+		clingy.Repeated,
+	).([]string)
+
+	c.kind = params.Flag("kind", "graph to export: units or callgraph", "units").(string)
+	c.format = params.Flag("format", "output format: dot, json, or mermaid", "dot").(string)
+	c.output = params.Flag("output", "output file, stdout if empty", "").(string)
+	c.reportPath = params.Flag("report", "JSON report used to color nodes by issue severity", "").(string)
+	c.pkg = params.Flag("package", "only include nodes from packages matching the pattern", "").(string)
+	c.focus = params.Flag("focus", "only include the neighborhood of this function or unit ID", "").(string)
+
+	c.depth = params.Flag("depth", "neighborhood depth for -focus", 1,
+		clingy.Transform(strconv.Atoi),
+	).(int)
+
+	c.allowErrors = params.Flag("allow-errors", "skip packages with errors instead of failing", false,
+		clingy.Transform(strconv.ParseBool), clingy.Boolean,
+	).(bool)
+
+	c.patterns = params.Arg("patterns", "packages to analyze",
+		clingy.Optional,
+		clingy.Repeated,
+	).([]string)
+}
+
+func (c *cmdGraph) Execute(ctx context.Context) error {
+	patterns := c.patterns
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	cfg, err := config.LoadConfig(c.configPaths, c.inlineConfigs)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	// Progress goes to stderr so the graph can be piped
+	l, err := loadUnits(os.Stderr, cfg, c.allowErrors, ".", patterns)
+	if err != nil {
+		return err
+	}
+
+	var g *graph.Graph
+	switch c.kind {
+	case "units":
+		g = graph.FromUnits(l.units)
+	case "callgraph":
+		g = graph.FromCallgraph(l.funcs, l.graph)
+	default:
+		return fmt.Errorf("unknown graph kind %q", c.kind)
+	}
+
+	if c.reportPath != "" {
+		rpt, err := report.ReadJSONFile(c.reportPath)
+		if err != nil {
+			return fmt.Errorf("read report: %w", err)
+		}
+		g.Annotate(rpt)
+	}
+
+	if c.pkg != "" {
+		pattern, err := extract.CompilePattern(c.pkg)
+		if err != nil {
+			return err
+		}
+		g.FilterPackages(pattern)
+	}
+
+	if c.focus != "" {
+		if !g.Neighborhood(c.focus, c.depth) {
+			return fmt.Errorf("function or unit %q not found", c.focus)
+		}
+	}
+
+	var write func(io.Writer, *graph.Graph) error
+	switch c.format {
+	case "dot":
+		write = graph.WriteDOT
+	case "json":
+		write = graph.WriteJSON
+	case "mermaid":
+		write = graph.WriteMermaid
+	default:
+		return fmt.Errorf("unknown format %q", c.format)
+	}
+
+	if c.output == "" {
+		return write(os.Stdout, g)
+	}
+
+	f, err := os.Create(c.output)
+	if err != nil {
+		return err
+	}
+	if err := write(f, g); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
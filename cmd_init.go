@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/zeebo/clingy"
+
+	"github.com/loov/dreamlint/analyze"
+	"github.com/loov/dreamlint/llm"
+)
+
+type cmdInit struct {
+	configPath string
+	baseURL    string
+	model      string
+	promptsDir string
+	force      bool
+}
+
+func (c *cmdInit) Setup(params clingy.Parameters) {
+	c.configPath = params.Flag("config", "path of the config file to create", "dreamlint.cue").(string)
+	c.baseURL = params.Flag("base-url", "OpenAI-compatible API URL, local servers are probed if empty", "").(string)
+	c.model = params.Flag("model", "model to use, the first available model if empty", "").(string)
+	c.promptsDir = params.Flag("prompts", "directory to copy the builtin prompts to for customization", "").(string)
+
+	c.force = params.Flag("force", "overwrite an existing config file", false,
+		clingy.Transform(strconv.ParseBool), clingy.Boolean,
+	).(bool)
+}
+
+// localServers are probed for models when no base URL is given.
+var localServers = []string{
+	"http://localhost:1234/v1",  // LM Studio
+	"http://localhost:11434/v1", // Ollama
+}
+
+// defaultModel is used when no model is given and no server could be probed.
+const defaultModel = "qwen/qwen3-coder-30b:8bit"
+
+// initPasses lists the builtin passes in the generated config.
+var initPasses = []struct {
+	Name        string
+	Description string
+}{
+	{"summary", "Summarize function behavior for use by other passes"},
+	{"baseline", "Simple baseline analysis with very little context"},
+	{"security", "Find security vulnerabilities"},
+	{"correctness", "Find bugs in error handling, nil safety, and resource management"},
+	{"concurrency", "Find race conditions and goroutine issues"},
+	{"maintainability", "Find complexity and readability issues"},
+}
+
+var initConfigTemplate = template.Must(template.New("dreamlint.cue").Parse(`// {{.Path}} - dreamlint configuration
+
+package config
+
+llm: {
+	provider:    "openai"
+	base_url:    {{printf "%q" .BaseURL}}
+{{- if .Models}}
+	// Available models:
+{{- range .Models}}
+	//   {{.}}
+{{- end}}
+{{- end}}
+	model:       {{printf "%q" .Model}}
+	max_tokens:  4096
+	temperature: 0.1
+}
+
+cache: {
+	dir:     ".dreamlint/cache"
+	enabled: true
+}
+
+output: {
+	json:     "dreamlint-report.json"
+	markdown: "dreamlint-report.md"
+	sarif:    "dreamlint-report.sarif"
+	junit:    "dreamlint-report.junit.xml"
+}
+{{range .Passes}}
+pass: {{.Name}}: {
+	prompt:      {{printf "%q" .Prompt}}
+	description: {{printf "%q" .Description}}
+}
+{{- end}}
+
+// Run only specific analysis passes:
+// analyse: [pass.summary, pass.correctness]
+`))
+
+type initConfig struct {
+	Path    string
+	BaseURL string
+	Model   string
+	Models  []string
+	Passes  []initPass
+}
+
+type initPass struct {
+	Name        string
+	Prompt      string
+	Description string
+}
+
+func (c *cmdInit) Execute(ctx context.Context) error {
+	if _, err := os.Stat(c.configPath); err == nil && !c.force {
+		return fmt.Errorf("%s already exists, use -force to overwrite it", c.configPath)
+	}
+
+	cfg := initConfig{
+		Path:    filepath.Base(c.configPath),
+		BaseURL: c.baseURL,
+		Model:   c.model,
+	}
+
+	servers := localServers
+	if c.baseURL != "" {
+		servers = []string{c.baseURL}
+	}
+	for _, server := range servers {
+		models, err := probeModels(ctx, server)
+		if err != nil {
+			fmt.Printf("No server at %s: %v\n", server, err)
+			continue
+		}
+		fmt.Printf("Found %d model(s) at %s\n", len(models), server)
+		cfg.BaseURL = server
+		cfg.Models = models
+		break
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = servers[0]
+	}
+	if cfg.Model == "" {
+		cfg.Model = defaultModel
+		if len(cfg.Models) > 0 {
+			cfg.Model = cfg.Models[0]
+		}
+	}
+
+	for _, pass := range initPasses {
+		prompt := "builtin:" + pass.Name
+		if c.promptsDir != "" {
+			prompt = path.Join(filepath.ToSlash(c.promptsDir), pass.Name+".txt")
+		}
+		cfg.Passes = append(cfg.Passes, initPass{
+			Name:        pass.Name,
+			Prompt:      prompt,
+			Description: pass.Description,
+		})
+	}
+
+	// Copy prompts first, so that the config does not refer to missing prompts
+	if c.promptsDir != "" {
+		if err := os.CopyFS(c.promptsDir, analyze.BuiltinPrompts()); err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				return fmt.Errorf("copy prompts: %w, remove the existing prompts first", err)
+			}
+			return fmt.Errorf("copy prompts: %w", err)
+		}
+		fmt.Printf("Copied builtin prompts to %s\n", c.promptsDir)
+	}
+
+	var b strings.Builder
+	if err := initConfigTemplate.Execute(&b, cfg); err != nil {
+		return fmt.Errorf("render config: %w", err)
+	}
+	if err := os.WriteFile(c.configPath, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	fmt.Printf("Wrote %s using model %s\n", c.configPath, cfg.Model)
+
+	// dreamlint is run where its config is, so the cache is created next to it
+	gitignore := filepath.Join(filepath.Dir(c.configPath), ".gitignore")
+	added, err := ignoreDir(gitignore, ".dreamlint/")
+	if err != nil {
+		return fmt.Errorf("update %s: %w", gitignore, err)
+	}
+	if added {
+		fmt.Printf("Added .dreamlint/ to %s\n", gitignore)
+	}
+	return nil
+}
+
+// probeModels lists the models of the OpenAI-compatible server at baseURL.
+func probeModels(ctx context.Context, baseURL string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	return llm.NewOpenAIClient(baseURL, "").Models(ctx)
+}
+
+// ignoreDir adds dir to the gitignore file unless it's already listed.
+// It reports whether the file was changed.
+func ignoreDir(gitignore, dir string) (bool, error) {
+	data, err := os.ReadFile(gitignore)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+
+	name := strings.Trim(dir, "/")
+	lines := strings.Split(string(data), "\n")
+	if slices.ContainsFunc(lines, func(line string) bool {
+		return strings.Trim(strings.TrimSpace(line), "/") == name
+	}) {
+		return false, nil
+	}
+
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		data = append(data, '\n')
+	}
+	data = append(data, dir+"\n"...)
+	return true, os.WriteFile(gitignore, data, 0644)
+}
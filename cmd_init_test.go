@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/loov/dreamlint/config"
+)
+
+func TestIgnoreDir(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial *string
+		added   bool
+		want    string
+	}{
+		{"missing", nil, true, ".dreamlint/\n"},
+		{"no trailing newline", ptr("bin"), true, "bin\n.dreamlint/\n"},
+		{"listed", ptr("bin\n.dreamlint/\n"), false, "bin\n.dreamlint/\n"},
+		{"listed without slash", ptr("/.dreamlint\n"), false, "/.dreamlint\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".gitignore")
+			if test.initial != nil {
+				if err := os.WriteFile(path, []byte(*test.initial), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			added, err := ignoreDir(path, ".dreamlint/")
+			if err != nil {
+				t.Fatalf("ignoreDir: %v", err)
+			}
+			if added != test.added {
+				t.Errorf("added = %v, want %v", added, test.added)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != test.want {
+				t.Errorf("got %q, want %q", data, test.want)
+			}
+		})
+	}
+}
+
+func TestInitConfigLoads(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": [{"id": "small"}, {"id": "big"}]}`))
+	}))
+	defer server.Close()
+
+	for _, promptsDir := range []string{"", "prompts"} {
+		t.Run("prompts="+promptsDir, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+
+			cmd := &cmdInit{configPath: "dreamlint.cue", baseURL: server.URL, promptsDir: promptsDir}
+			if err := cmd.Execute(context.Background()); err != nil {
+				t.Fatalf("init: %v", err)
+			}
+
+			cfg, err := config.LoadConfig([]string{"dreamlint.cue"}, nil)
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.LLM.BaseURL != server.URL || cfg.LLM.Model != "small" {
+				t.Errorf("llm = %+v, want the first model of the server", cfg.LLM)
+			}
+			if len(cfg.Analyse) != len(initPasses) {
+				t.Errorf("got %d passes, want %d", len(cfg.Analyse), len(initPasses))
+			}
+			if err := cfg.Validate(); err != nil {
+				t.Errorf("Validate: %v", err)
+			}
+		})
+	}
+}
+
+func TestInitGitignore(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": [{"id": "small"}]}`))
+	}))
+	defer server.Close()
+
+	t.Chdir(t.TempDir())
+	if err := os.Mkdir("sub", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &cmdInit{configPath: filepath.Join("sub", "dreamlint.cue"), baseURL: server.URL}
+	if err := cmd.Execute(context.Background()); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	if _, err := os.Stat(".gitignore"); err == nil {
+		t.Error(".gitignore was written to the working directory")
+	}
+	data, err := os.ReadFile(filepath.Join("sub", ".gitignore"))
+	if err != nil || string(data) != ".dreamlint/\n" {
+		t.Errorf("sub/.gitignore = %q, %v", data, err)
+	}
+}
+
+func ptr(s string) *string { return &s }
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/rogpeppe/go-internal/diff"
+	"github.com/zeebo/clingy"
+
+	"github.com/loov/dreamlint/analyze"
+	"github.com/loov/dreamlint/config"
+	"github.com/loov/dreamlint/extract"
+	"github.com/loov/dreamlint/report"
+)
+
+type cmdPrompt struct {
+	configPaths   []string
+	inlineConfigs []string
+	passes        []string
+	reportPath    string
+	promptsDir    string
+	diffDir       string
+	allowErrors   bool
+	target        string
+	patterns      []string
+}
+
+func (c *cmdPrompt) Setup(params clingy.Parameters) {
+	c.configPaths = params.Flag("config", "path to config file",
+		[]string{"dreamlint.cue"},
+		clingy.Repeated,
+	).([]string)
+
+	c.inlineConfigs = params.Flag("c", "inline CUE config",
+		[]string{},
+		clingy.Repeated,
+	).([]string)
+
+	c.passes = params.Flag("pass", "pass to render, all enabled passes if not set",
+		[]string{},
+		clingy.Repeated,
+	).([]string)
+
+	c.reportPath = params.Flag("report", "JSON report to take summaries from", "").(string)
+	c.promptsDir = params.Flag("prompts", "directory to load prompts from", "").(string)
+	c.diffDir = params.Flag("diff", "previous prompts directory to diff the rendered prompts against", "").(string)
+
+	c.allowErrors = params.Flag("allow-errors", "skip packages with errors instead of failing", false,
+		clingy.Transform(strconv.ParseBool), clingy.Boolean,
+	).(bool)
+
+	c.target = params.Arg("function", "function or unit ID, or file.go:line").(string)
+
+	c.patterns = params.Arg("patterns", "packages to analyze",
+		clingy.Optional,
+		clingy.Repeated,
+	).([]string)
+}
+
+func (c *cmdPrompt) Execute(ctx context.Context) error {
+	patterns := c.patterns
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	cfg, err := config.LoadConfig(c.configPaths, c.inlineConfigs)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	for _, name := range c.passes {
+		if !slices.ContainsFunc(cfg.AllPasses(), func(pass config.AnalysisPass) bool { return pass.Name == name }) {
+			return fmt.Errorf("unknown pass %q", name)
+		}
+	}
+
+	// Progress goes to stderr so the prompts can be piped
+	l, err := loadUnits(os.Stderr, cfg, c.allowErrors, ".", patterns)
+	if err != nil {
+		return err
+	}
+
+	target, err := findUnit(l, c.target)
+	if err != nil {
+		return err
+	}
+
+	pipeline, err := loadPipeline(cfg, nil, l.externalFuncs, c.promptsDir)
+	if err != nil {
+		return err
+	}
+
+	// Summaries from the report take precedence over cached summaries
+	var known map[string]*analyze.SummaryResponse
+	if c.reportPath != "" {
+		rpt, err := report.ReadJSONFile(c.reportPath)
+		if err != nil {
+			return fmt.Errorf("read report: %w", err)
+		}
+		known = reportSummaries(rpt)
+	}
+	summaries := cachedSummaries(pipeline, extract.CalleeClosure(l.units, target), known)
+
+	for _, callee := range target.Callees {
+		if _, ok := summaries[callee]; !ok {
+			fmt.Fprintf(os.Stderr, "Warning: no summary for callee %s, it is omitted from the prompts\n", callee)
+		}
+	}
+	summary, ok := summaries[target.ID]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Warning: no summary for %s, analysis prompts are rendered without it\n", target.ID)
+	}
+
+	prompts, err := renderSelectedPrompts(pipeline, target, summaries, summary, c.passes)
+	if err != nil {
+		return err
+	}
+
+	if c.diffDir == "" {
+		return writePrompts(os.Stdout, prompts)
+	}
+
+	previous, err := loadPipeline(cfg, nil, l.externalFuncs, c.diffDir)
+	if err != nil {
+		return fmt.Errorf("previous prompts: %w", err)
+	}
+	previousPrompts, err := renderSelectedPrompts(previous, target, summaries, summary, c.passes)
+	if err != nil {
+		return fmt.Errorf("previous prompts: %w", err)
+	}
+
+	current := c.promptsDir
+	if current == "" {
+		current = "builtin"
+	}
+	return writePromptsDiff(os.Stdout, c.diffDir, previousPrompts, current, prompts)
+}
+
+// renderSelectedPrompts renders the prompts of unit, limited to passes when not empty.
+func renderSelectedPrompts(pipeline *analyze.Pipeline, unit *extract.AnalysisUnit, summaries map[string]*analyze.SummaryResponse, summary *analyze.SummaryResponse, passes []string) ([]analyze.PassPrompt, error) {
+	prompts, err := pipeline.RenderPrompts(unit, summaries, summary)
+	if err != nil {
+		return nil, err
+	}
+	if len(passes) == 0 {
+		return prompts, nil
+	}
+	return slices.DeleteFunc(prompts, func(prompt analyze.PassPrompt) bool {
+		return !slices.Contains(passes, prompt.Pass)
+	}), nil
+}
+
+// writePrompts writes each prompt followed by its response schema.
+func writePrompts(w io.Writer, prompts []analyze.PassPrompt) error {
+	for i, prompt := range prompts {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "== %s prompt ==\n%s\n", prompt.Pass, prompt.Prompt)
+
+		schema, err := json.MarshalIndent(prompt.Schema.Schema, "", "  ")
+		if err != nil {
+			return fmt.Errorf("%s schema: %w", prompt.Pass, err)
+		}
+		fmt.Fprintf(w, "\n== %s schema %q ==\n%s\n", prompt.Pass, prompt.Schema.Name, schema)
+	}
+	return nil
+}
+
+// writePromptsDiff writes a unified diff of the prompts rendered from two prompt directories.
+// Passes rendered only from oldDir are shown as removed.
+func writePromptsDiff(w io.Writer, oldDir string, oldPrompts []analyze.PassPrompt, newDir string, newPrompts []analyze.PassPrompt) error {
+	oldByPass := make(map[string]string)
+	for _, prompt := range oldPrompts {
+		oldByPass[prompt.Pass] = prompt.Prompt
+	}
+
+	for _, prompt := range newPrompts {
+		d := diff.Diff(
+			filepath.Join(oldDir, prompt.Pass), []byte(oldByPass[prompt.Pass]),
+			filepath.Join(newDir, prompt.Pass), []byte(prompt.Prompt),
+		)
+		delete(oldByPass, prompt.Pass)
+		if d == nil {
+			fmt.Fprintf(w, "== %s: no differences ==\n", prompt.Pass)
+			continue
+		}
+		if _, err := w.Write(d); err != nil {
+			return err
+		}
+	}
+
+	for _, prompt := range oldPrompts {
+		if _, ok := oldByPass[prompt.Pass]; !ok {
+			continue
+		}
+		d := diff.Diff(
+			filepath.Join(oldDir, prompt.Pass), []byte(prompt.Prompt),
+			filepath.Join(newDir, prompt.Pass), nil,
+		)
+		if _, err := w.Write(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/loov/dreamlint/analyze"
+)
+
+func TestWritePromptsDiff(t *testing.T) {
+	oldPrompts := []analyze.PassPrompt{
+		{Pass: "summary", Prompt: "summarize\n"},
+		{Pass: "security", Prompt: "find vulnerabilities\n"},
+	}
+	newPrompts := []analyze.PassPrompt{
+		{Pass: "summary", Prompt: "summarize\n"},
+		{Pass: "correctness", Prompt: "find bugs\n"},
+	}
+
+	var b strings.Builder
+	if err := writePromptsDiff(&b, "old", oldPrompts, "new", newPrompts); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"== summary: no differences ==",
+		"+find bugs",
+		"-find vulnerabilities",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("diff does not contain %q:\n%s", want, out)
+		}
+	}
+}
@@ -5,19 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/mattn/go-isatty"
 	"github.com/zeebo/clingy"
 
 	"github.com/loov/dreamlint/analyze"
-	"github.com/loov/dreamlint/cache"
 	"github.com/loov/dreamlint/config"
-	"github.com/loov/dreamlint/extract"
-	"github.com/loov/dreamlint/llm"
 	"github.com/loov/dreamlint/report"
+	"github.com/loov/dreamlint/report/junit"
 	"github.com/loov/dreamlint/report/markdown"
 	"github.com/loov/dreamlint/report/sarif"
+	"github.com/loov/dreamlint/triage"
 )
 
 type cmdRun struct {
@@ -25,7 +26,10 @@ type cmdRun struct {
 	inlineConfigs []string
 	format        string
 	resume        bool
+	allowErrors   bool
 	promptsDir    string
+	pathPrefix    string
+	absolutePaths bool
 	patterns      []string
 }
 
@@ -40,14 +44,24 @@ func (c *cmdRun) Setup(params clingy.Parameters) {
 		clingy.Repeated,
 	).([]string)
 
-	c.format = params.Flag("format", "output format: json, markdown, sarif, or all", "all").(string)
+	c.format = params.Flag("format", "output format: json, markdown, sarif, junit, or all", "all").(string)
 
 	c.resume = params.Flag("resume", "resume from existing partial report", false,
-		clingy.Boolean,
+		clingy.Transform(strconv.ParseBool), clingy.Boolean,
+	).(bool)
+
+	c.allowErrors = params.Flag("allow-errors", "skip packages with errors instead of failing", false,
+		clingy.Transform(strconv.ParseBool), clingy.Boolean,
 	).(bool)
 
 	c.promptsDir = params.Flag("prompts", "directory to load prompts from", "").(string)
 
+	c.pathPrefix = params.Flag("path-prefix", "prefix for report paths, e.g. the module directory within the repository", "").(string)
+
+	c.absolutePaths = params.Flag("absolute-paths", "write absolute file paths instead of paths relative to the module root", false,
+		clingy.Transform(strconv.ParseBool), clingy.Boolean,
+	).(bool)
+
 	c.patterns = params.Arg("patterns", "packages to analyze",
 		clingy.Optional,
 		clingy.Repeated,
@@ -60,7 +74,7 @@ func (c *cmdRun) Execute(ctx context.Context) error {
 		patterns = []string{"./..."}
 	}
 
-	return run(c.configPaths, c.inlineConfigs, c.format, c.resume, c.promptsDir, patterns)
+	return c.run(patterns)
 }
 
 // isTTY reports whether stdout is a terminal.
@@ -81,60 +95,30 @@ func printProgress(format string, args ...any) {
 	}
 }
 
-func run(configPaths, inlineConfigs []string, format string, resume bool, promptsDir string, patterns []string) error {
+func (c *cmdRun) run(patterns []string) error {
 	// Load config
-	cfg, err := config.LoadConfig(configPaths, inlineConfigs)
+	cfg, err := config.LoadConfig(c.configPaths, c.inlineConfigs)
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
 	}
 
-	// Create LLM client
-	client := llm.NewOpenAIClient(cfg.LLM.BaseURL, cfg.LLM.APIKey)
-
-	// Create cache
-	var c *cache.Cache
-	if cfg.Cache.Enabled {
-		c = cache.New(cfg.Cache.Dir)
-	}
-
-	// Load packages once
-	fmt.Println("Loading packages...")
-	pkgs, err := extract.LoadPackages(".", patterns...)
+	// Load packages and build analysis units
+	l, err := loadUnits(os.Stdout, cfg, c.allowErrors, ".", patterns)
 	if err != nil {
-		return fmt.Errorf("load packages: %w", err)
+		return err
 	}
-
-	// Extract functions
-	fmt.Println("Extracting functions...")
-	funcs := extract.ExtractFunctions(pkgs)
-	fmt.Printf("Found %d functions\n", len(funcs))
-
-	// Build callgraph
-	fmt.Println("Building callgraph...")
-	graph := extract.BuildCallgraph(pkgs)
-
-	// Extract external function info
-	fmt.Println("Extracting external functions...")
-	externalFuncs := extract.ExtractExternalFuncs(pkgs, graph)
-	fmt.Printf("Found %d external functions\n", len(externalFuncs))
-
-	// Build analysis units
-	fmt.Println("Building analysis units...")
-	units := extract.BuildAnalysisUnits(funcs, graph)
-	fmt.Printf("Created %d analysis units\n", len(units))
+	units := l.units
 
 	// Create pipeline
-	pipeline := analyze.NewPipeline(cfg, c, client, externalFuncs)
-	if promptsDir != "" {
-		pipeline.SetPromptsFS(os.DirFS(promptsDir))
-	}
-	if err := pipeline.LoadPrompts(); err != nil {
-		return fmt.Errorf("load prompts: %w", err)
+	pipeline, err := loadPipeline(cfg, nil, l.externalFuncs, c.promptsDir)
+	if err != nil {
+		return err
 	}
+	pipeline.SetFixChecker(l.pkgs.CheckFile)
 
 	// Load or create report
 	var rpt *report.Report
-	if resume {
+	if c.resume {
 		rpt, err = report.ReadJSONFile(cfg.Output.JSON)
 		if err != nil {
 			if errors.Is(err, os.ErrNotExist) {
@@ -151,35 +135,73 @@ func run(configPaths, inlineConfigs []string, format string, resume bool, prompt
 	if rpt == nil {
 		rpt = report.NewReport()
 		rpt.Metadata.Modules = patterns
-		rpt.Metadata.ConfigFiles = configPaths
-		rpt.Metadata.InlineConfigs = inlineConfigs
+		rpt.Metadata.ConfigFiles = c.configPaths
+		for _, inline := range c.inlineConfigs {
+			// Inline configs may contain literal API keys
+			rpt.Metadata.InlineConfigs = append(rpt.Metadata.InlineConfigs, config.RedactSource(inline))
+		}
 		rpt.Metadata.TotalUnits = len(units)
 		rpt.Metadata.GeneratedAt = time.Now()
 	}
 
+	// Skipped packages, severities and passes reflect the current run, also when resuming
+	rpt.Metadata.Severities = cfg.Severities
+	rpt.Metadata.Passes = passInfos(cfg)
+	rpt.Metadata.CompletedAt = time.Time{}
+
+	// Report paths are relative to the module root, unless asked otherwise
+	var paths report.Paths
+	if !c.absolutePaths {
+		paths, err = reportPaths(l.pkgs.ModuleRoot(), c.pathPrefix)
+		if err != nil {
+			return err
+		}
+	}
+	rpt.Metadata.Root = paths.SourceRoot()
+	rpt.Metadata.SkippedPackages = nil
+	for _, skipped := range l.pkgs.Skipped {
+		rpt.Metadata.SkippedPackages = append(rpt.Metadata.SkippedPackages, report.SkippedPackage{
+			Package: skipped.Path,
+			Errors:  skipped.Errors,
+		})
+	}
+
+	// Triage decisions suppress or carry forward issues
+	decisions := &triage.Decisions{}
+	if cfg.Triage.File != "" {
+		decisions, err = triage.Load(cfg.Triage.File)
+		if err != nil {
+			return fmt.Errorf("load triage decisions: %w", err)
+		}
+	}
+
 	// Analyze each unit in order
 	ctx := context.Background()
-	calleeSummaries := make(map[string]*analyze.SummaryResponse)
 
 	// Rebuild callee summaries from existing report for resumption
-	for unitID, unitReport := range rpt.Units {
-		calleeSummaries[unitID] = &analyze.SummaryResponse{
-			Purpose:    unitReport.Summary.Purpose,
-			Behavior:   unitReport.Summary.Behavior,
-			Invariants: unitReport.Summary.Invariants,
-			Security:   unitReport.Summary.Security,
-		}
-	}
+	calleeSummaries := reportSummaries(rpt)
 
 	skipped := 0
 	analyzed := 0
 	var lastErr error
 
 	// Track issues found during analysis for live display
+	severities := rpt.Severities()
 	issuesBySeverity := make(map[string]int)
 	var currentPhase string
 
 	pipeline.OnProgress(func(event analyze.ProgressEvent) {
+		if event.Fallback != nil {
+			clearLine()
+			fmt.Printf("    ! %s failed: %v, trying %s\n", event.Fallback.Model, event.Fallback.Err, event.Fallback.Next)
+			// Print the phase again below the message
+			currentPhase = ""
+		}
+		if event.FixError != nil {
+			clearLine()
+			fmt.Printf("    ! %v\n", event.FixError)
+			currentPhase = ""
+		}
 		if event.Phase != currentPhase {
 			currentPhase = event.Phase
 			printProgress("    → %s", currentPhase)
@@ -190,7 +212,7 @@ func run(configPaths, inlineConfigs []string, format string, resume bool, prompt
 			if isTTY {
 				fmt.Print("\r\033[K    → ", currentPhase, " [")
 				first := true
-				for _, sev := range []string{"critical", "important", "minor"} {
+				for _, sev := range severities.Names() {
 					if count := issuesBySeverity[sev]; count > 0 {
 						if !first {
 							fmt.Print(" ")
@@ -223,12 +245,19 @@ func run(configPaths, inlineConfigs []string, format string, resume bool, prompt
 			lastErr = fmt.Errorf("analyze %s: %w", unit.ID, err)
 			fmt.Printf("Error: %v\n", lastErr)
 			fmt.Println("Saving progress...")
-			saveProgress(rpt, cfg, format)
+			saveProgress(rpt, cfg, c.format)
 			fmt.Printf("Progress saved. Run with -resume to continue.\n")
 			return lastErr
 		}
 
-		rpt.Units[unit.ID] = *unitReport
+		paths.Unit(unitReport)
+		bodies := make(map[string]string, len(unit.Functions))
+		for _, fn := range unit.Functions {
+			bodies[fn.ID()] = fn.Body
+		}
+		suppressed := decisions.Apply(unit.ID, unitReport, bodies)
+		rpt.Summary.Suppressed += suppressed
+		rpt.AddUnit(unit.ID, *unitReport)
 		analyzed++
 
 		// Print unit summary
@@ -237,35 +266,18 @@ func run(configPaths, inlineConfigs []string, format string, resume bool, prompt
 		} else {
 			fmt.Printf("    Found %d issue(s)\n", len(unitReport.Issues))
 		}
+		if suppressed > 0 {
+			fmt.Printf("    Suppressed %d triaged issue(s)\n", suppressed)
+		}
 
 		// Store summary for callers
 		if summary := pipeline.GetSummary(unit.ID); summary != nil {
 			calleeSummaries[unit.ID] = summary
 		}
 
-		// Update report summary
-		for _, issue := range unitReport.Issues {
-			rpt.Summary.TotalIssues++
-			rpt.Summary.BySeverity[string(issue.Severity)]++
-			rpt.Summary.ByCategory[issue.Category]++
-
-			if issue.Severity == report.SeverityCritical {
-				found := false
-				for _, u := range rpt.Summary.CriticalUnits {
-					if u == unit.ID {
-						found = true
-						break
-					}
-				}
-				if !found {
-					rpt.Summary.CriticalUnits = append(rpt.Summary.CriticalUnits, unit.ID)
-				}
-			}
-		}
-
 		// Save progress periodically (every 10 units)
 		if analyzed%10 == 0 {
-			saveProgress(rpt, cfg, format)
+			saveProgress(rpt, cfg, c.format)
 		}
 	}
 
@@ -274,19 +286,40 @@ func run(configPaths, inlineConfigs []string, format string, resume bool, prompt
 	}
 
 	// Write final output
-	if err := writeReport(rpt, cfg, format, true); err != nil {
+	rpt.Metadata.CompletedAt = time.Now()
+	if err := writeReport(rpt, cfg, c.format, true); err != nil {
 		return err
 	}
 
 	// Print summary
 	fmt.Printf("\nAnalysis complete: %d issues found\n", rpt.Summary.TotalIssues)
-	for sev, count := range rpt.Summary.BySeverity {
-		fmt.Printf("  %s: %d\n", sev, count)
+	names := make([]string, 0, len(rpt.Summary.BySeverity))
+	for sev := range rpt.Summary.BySeverity {
+		names = append(names, sev)
+	}
+	severities.Sort(names)
+	for _, sev := range names {
+		fmt.Printf("  %s: %d\n", sev, rpt.Summary.BySeverity[sev])
+	}
+	if rpt.Summary.Suppressed > 0 {
+		fmt.Printf("  suppressed by triage: %d\n", rpt.Summary.Suppressed)
 	}
 
 	return nil
 }
 
+// passInfos describes the passes of cfg, including the passes of overrides.
+func passInfos(cfg *config.Config) []report.PassInfo {
+	var infos []report.PassInfo
+	for _, pass := range cfg.AllPasses() {
+		if slices.ContainsFunc(infos, func(info report.PassInfo) bool { return info.Name == pass.Name }) {
+			continue
+		}
+		infos = append(infos, report.PassInfo{Name: pass.Name, Description: pass.Description})
+	}
+	return infos
+}
+
 func writeReport(rpt *report.Report, cfg *config.Config, format string, final bool) error {
 	if format == "json" || format == "all" {
 		if err := report.WriteJSONFile(rpt, cfg.Output.JSON); err != nil {
@@ -312,6 +345,18 @@ func writeReport(rpt *report.Report, cfg *config.Config, format string, final bo
 			fmt.Printf("Wrote %s\n", cfg.Output.SARIF)
 		}
 	}
+	if format == "junit" || format == "all" {
+		opts := junit.Options{
+			MinSeverity: report.Severity(cfg.JUnit.MinSeverity),
+			ByPackage:   cfg.JUnit.TestCase == "package",
+		}
+		if err := junit.WriteFile(rpt, cfg.Output.JUnit, opts); err != nil {
+			return fmt.Errorf("write junit: %w", err)
+		}
+		if final {
+			fmt.Printf("Wrote %s\n", cfg.Output.JUnit)
+		}
+	}
 	return nil
 }
 
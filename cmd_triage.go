@@ -0,0 +1,457 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/zeebo/clingy"
+
+	"github.com/loov/dreamlint/config"
+	"github.com/loov/dreamlint/report"
+	"github.com/loov/dreamlint/triage"
+)
+
+type cmdTriage struct {
+	configPaths   []string
+	inlineConfigs []string
+	categories    []string
+	severity      string
+	units         []string
+	all           bool
+	reportPath    string
+}
+
+func (c *cmdTriage) Setup(params clingy.Parameters) {
+	c.configPaths = params.Flag("config", "path to config file",
+		[]string{"dreamlint.cue"},
+		clingy.Repeated,
+	).([]string)
+
+	c.inlineConfigs = params.Flag("c", "inline CUE config",
+		[]string{},
+		clingy.Repeated,
+	).([]string)
+
+	c.categories = params.Flag("category", "triage only issues of the category, all categories if not set",
+		[]string{},
+		clingy.Repeated,
+	).([]string)
+
+	c.severity = params.Flag("severity", "triage only issues of at least the severity", "").(string)
+
+	c.units = params.Flag("unit", "triage only issues of units matching the pattern, all units if not set",
+		[]string{},
+		clingy.Repeated,
+	).([]string)
+
+	c.all = params.Flag("all", "include issues that were already triaged", false,
+		clingy.Transform(strconv.ParseBool), clingy.Boolean,
+	).(bool)
+
+	c.reportPath = params.Arg("report", "JSON report to triage the issues of").(string)
+}
+
+func (c *cmdTriage) Execute(ctx context.Context) error {
+	cfg, err := config.LoadConfig(c.configPaths, c.inlineConfigs)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if cfg.Triage.File == "" {
+		return errors.New("triage.file is not set in the config")
+	}
+
+	rpt, err := report.ReadJSONFile(c.reportPath)
+	if err != nil {
+		return fmt.Errorf("read report: %w", err)
+	}
+	decisions, err := triage.Load(cfg.Triage.File)
+	if err != nil {
+		return fmt.Errorf("load triage decisions: %w", err)
+	}
+
+	selector, err := newIssueSelector(rpt, c.categories, c.severity, c.units)
+	if err != nil {
+		return err
+	}
+	items := triageItems(rpt, selector.Select(rpt), decisions, c.all)
+	if len(items) == 0 {
+		fmt.Println("No issues to triage")
+		return nil
+	}
+
+	session := &triageSession{
+		rpt:       rpt,
+		items:     items,
+		decisions: decisions,
+		save:      func() error { return decisions.Save(cfg.Triage.File) },
+		out:       os.Stdout,
+	}
+
+	var keys keyReader = lineKeys{bufio.NewScanner(os.Stdin)}
+	var restore func() error
+	if fd := os.Stdin.Fd(); isatty.IsTerminal(fd) {
+		restore, err = makeRaw(int(fd))
+		if err == nil {
+			keys = rawKeys{bufio.NewReader(os.Stdin)}
+			session.screen = true
+			session.width, session.height, _ = terminalSize(int(os.Stdout.Fd()))
+			// Use the alternate screen, so the terminal is left as it was
+			fmt.Print("\x1b[?1049h")
+		}
+	}
+
+	err = session.run(keys)
+	if session.screen {
+		fmt.Print("\x1b[?1049l")
+		_ = restore()
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Triaged %d issue(s), decisions are in %s\n", session.decided, cfg.Triage.File)
+	return nil
+}
+
+// triageItems returns the issues to triage, the most severe first. Issues
+// with a decision are left out unless all is set.
+func triageItems(rpt *report.Report, selected []*selectedIssue, decisions *triage.Decisions, all bool) []*selectedIssue {
+	if !all {
+		selected = slices.DeleteFunc(selected, func(item *selectedIssue) bool {
+			_, decided := decisions.Lookup(item.unitID, *item.issue)
+			return decided
+		})
+	}
+	severities := rpt.Severities()
+	slices.SortStableFunc(selected, func(a, b *selectedIssue) int {
+		return severities.Compare(a.issue.Severity, b.issue.Severity)
+	})
+	return selected
+}
+
+// keyReader reads key presses, returning names such as "t", "enter" or "left".
+type keyReader interface {
+	ReadKey() (string, error)
+}
+
+// rawKeys reads single key presses from a terminal in raw mode.
+type rawKeys struct {
+	r *bufio.Reader
+}
+
+func (k rawKeys) ReadKey() (string, error) {
+	b, err := k.r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	switch b {
+	case 3, 4:
+		return "ctrl-c", nil
+	case '\r', '\n':
+		return "enter", nil
+	case 0x1b:
+		// Arrow keys arrive as a single escape sequence, e.g. "\x1b[C"
+		if k.r.Buffered() < 2 {
+			return "escape", nil
+		}
+		seq := make([]byte, 2)
+		if _, err := io.ReadFull(k.r, seq); err != nil {
+			return "", err
+		}
+		switch string(seq) {
+		case "[A":
+			return "up", nil
+		case "[B":
+			return "down", nil
+		case "[C":
+			return "right", nil
+		case "[D":
+			return "left", nil
+		}
+		return "escape", nil
+	}
+	return strings.ToLower(string(rune(b))), nil
+}
+
+// lineKeys reads keys as lines, when the input is not a terminal.
+type lineKeys struct {
+	s *bufio.Scanner
+}
+
+func (k lineKeys) ReadKey() (string, error) {
+	if !k.s.Scan() {
+		if err := k.s.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	key := strings.ToLower(strings.TrimSpace(k.s.Text()))
+	if key == "" {
+		return "enter", nil
+	}
+	return key, nil
+}
+
+// triageSession shows issues one at a time and records decisions about them.
+type triageSession struct {
+	rpt       *report.Report
+	items     []*selectedIssue
+	decisions *triage.Decisions
+	save      func() error
+	out       io.Writer
+
+	// screen is set when drawing to a terminal, which is width x height,
+	// or of unknown size when zero.
+	screen        bool
+	width, height int
+
+	index   int
+	decided int
+	// history holds the indexes of the decided items, the last decided last.
+	history []int
+	status  string
+	sources map[string][]string
+}
+
+// triageKeys describes the keys of a triage session.
+const triageKeys = "[t] true positive  [f] false positive  [w] won't fix  [u] undo  [n] next  [p] previous  [q] quit"
+
+// run shows the issues until the user quits. The session stays on the last
+// issue, so that its decision can still be undone.
+func (s *triageSession) run(keys keyReader) error {
+	for {
+		s.render()
+		key, err := keys.ReadKey()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		s.status = ""
+		switch key {
+		case "t":
+			err = s.decide(triage.TruePositive)
+		case "f":
+			err = s.decide(triage.FalsePositive)
+		case "w":
+			err = s.decide(triage.WontFix)
+		case "u":
+			err = s.undo()
+		case "n", "j", "enter", " ", "right", "down":
+			s.next()
+		case "p", "k", "left", "up":
+			if s.index > 0 {
+				s.index--
+			}
+		case "q", "ctrl-c", "escape":
+			return nil
+		default:
+			s.status = "Unknown key " + strconv.Quote(key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// next moves to the next issue, staying on the last one.
+func (s *triageSession) next() {
+	if s.index < len(s.items)-1 {
+		s.index++
+		return
+	}
+	s.status = joinStatus(s.status, "This is the last issue, [q] to quit")
+}
+
+// joinStatus appends msg to the status line.
+func joinStatus(status, msg string) string {
+	if status == "" {
+		return msg
+	}
+	return status + ". " + msg
+}
+
+// decide records the decision about the current issue and moves to the next one.
+func (s *triageSession) decide(decision triage.Decision) error {
+	item := s.items[s.index]
+	s.decisions.Set(item.unitID, *item.issue, decision)
+	if err := s.save(); err != nil {
+		return fmt.Errorf("save triage decisions: %w", err)
+	}
+	s.decided++
+	s.history = append(s.history, s.index)
+	s.status = fmt.Sprintf("Marked %s as %s", item.position(), decisionName(decision))
+	s.next()
+	return nil
+}
+
+// undo removes the last decision of the session and moves back to its
+// issue. Without decisions in the session, it removes the decision about
+// the current issue, e.g. one from an earlier session.
+func (s *triageSession) undo() error {
+	index := s.index
+	if n := len(s.history); n > 0 {
+		index = s.history[n-1]
+		s.history = s.history[:n-1]
+		s.decided--
+	}
+
+	item := s.items[index]
+	if _, ok := s.decisions.Lookup(item.unitID, *item.issue); !ok {
+		s.status = "No decision to undo"
+		return nil
+	}
+	s.decisions.Clear(item.unitID, *item.issue)
+	if err := s.save(); err != nil {
+		return fmt.Errorf("save triage decisions: %w", err)
+	}
+	s.index = index
+	s.status = fmt.Sprintf("Removed the decision about %s", item.position())
+	return nil
+}
+
+// render draws the current issue, with its code and the unit summary.
+func (s *triageSession) render() {
+	item := s.items[s.index]
+	issue := item.issue
+	unit := s.rpt.Units[item.unitID]
+
+	var lines []string
+	add := func(format string, args ...any) {
+		lines = append(lines, strings.Split(fmt.Sprintf(format, args...), "\n")...)
+	}
+
+	add("[%d/%d] %s  %s  %s", s.index+1, len(s.items), strings.ToUpper(string(issue.Severity)), issue.Category, item.position())
+	add("in %s", item.function.ID)
+	if entry, ok := s.decisions.Lookup(item.unitID, *issue); ok {
+		add("Decision: %s (%s)", decisionName(entry.Decision), entry.DecidedAt.Format("2006-01-02"))
+	}
+	add("")
+	add("%s", issue.Message)
+	if issue.Suggestion != "" {
+		add("Suggestion: %s", issue.Suggestion)
+	}
+	if issue.Confidence > 0 {
+		add("Confidence: %.2f", issue.Confidence)
+	}
+	if unit.Summary.Purpose != "" {
+		add("")
+		add("Purpose: %s", unit.Summary.Purpose)
+		add("Behavior: %s", unit.Summary.Behavior)
+	}
+	add("")
+
+	footer := []string{"", s.status, triageKeys}
+	codeHeight := 0
+	if s.height > 0 {
+		codeHeight = max(s.height-len(lines)-len(footer), 3)
+	}
+	lines = append(lines, s.code(item, codeHeight)...)
+	lines = append(lines, footer...)
+
+	if s.screen {
+		// Clear the screen and draw from the top
+		fmt.Fprint(s.out, "\x1b[H\x1b[2J")
+		for i, line := range lines {
+			lines[i] = truncate(line, s.width)
+		}
+	}
+	fmt.Fprintln(s.out, strings.Join(lines, "\n"))
+}
+
+// maxCodeLines is the number of code lines shown when the screen size is unknown.
+const maxCodeLines = 30
+
+// code returns the numbered lines of the function containing the issue, at
+// most height lines around the issue line, which is highlighted.
+func (s *triageSession) code(item *selectedIssue, height int) []string {
+	source, err := s.source(item.filename)
+	if err != nil {
+		return []string{fmt.Sprintf("(source not available: %v)", err)}
+	}
+	if height <= 0 {
+		height = maxCodeLines
+	}
+
+	line := item.issue.Position.Line
+	first, last := item.function.Position.Line, item.function.EndLine
+	if first <= 0 || first > line {
+		first = line
+	}
+	if last < line {
+		last = line
+	}
+	// Show the lines around the issue when the function does not fit
+	if last-first+1 > height {
+		first = max(first, line-height/2)
+		last = min(last, first+height-1)
+		first = max(first, last-height+1)
+	}
+	first, last = max(first, 1), min(last, len(source))
+
+	var lines []string
+	for n := first; n <= last; n++ {
+		text := strings.ReplaceAll(source[n-1], "\t", "    ")
+		if n != line {
+			lines = append(lines, fmt.Sprintf("  %5d │ %s", n, text))
+			continue
+		}
+		if s.screen {
+			text = "\x1b[7m" + text + "\x1b[0m"
+		}
+		lines = append(lines, fmt.Sprintf("> %5d │ %s", n, text))
+	}
+	return lines
+}
+
+// source returns the lines of filename.
+func (s *triageSession) source(filename string) ([]string, error) {
+	if lines, ok := s.sources[filename]; ok {
+		return lines, nil
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if s.sources == nil {
+		s.sources = make(map[string][]string)
+	}
+	lines := strings.Split(string(data), "\n")
+	s.sources[filename] = lines
+	return lines, nil
+}
+
+// decisionName returns a readable name of decision.
+func decisionName(decision triage.Decision) string {
+	switch decision {
+	case triage.TruePositive:
+		return "true positive"
+	case triage.FalsePositive:
+		return "false positive"
+	case triage.WontFix:
+		return "won't fix"
+	}
+	return string(decision)
+}
+
+// truncate shortens line to width runes, leaving escape sequences intact
+// for the highlighted line. Zero width does not truncate.
+func truncate(line string, width int) string {
+	if width <= 0 || strings.Contains(line, "\x1b[") {
+		return line
+	}
+	runes := []rune(line)
+	if len(runes) <= width {
+		return line
+	}
+	return string(runes[:width])
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/loov/dreamlint/report"
+	"github.com/loov/dreamlint/triage"
+)
+
+func TestTriageSession(t *testing.T) {
+	root := t.TempDir()
+	source := "package pkg\n\nfunc A(db DB, id string) {\n\tdb.Query(\"SELECT \" + id)\n\tdb.Close()\n}\n"
+	if err := os.WriteFile(filepath.Join(root, "a.go"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rpt := report.NewReport()
+	rpt.Metadata.Root = root
+	issue := func(severity report.Severity, line int, message string) report.Issue {
+		return report.Issue{
+			Position: token.Position{Filename: "a.go", Line: line},
+			Function: "pkg.A",
+			Severity: severity,
+			Category: "security",
+			Message:  message,
+			Snippet:  strings.TrimSpace(strings.Split(source, "\n")[line-1]),
+		}
+	}
+	rpt.AddUnit("pkg.A", report.UnitReport{
+		Functions: []report.FunctionInfo{{ID: "pkg.A", Position: token.Position{Filename: "a.go", Line: 3}, EndLine: 6}},
+		Summary:   report.FunctionSummary{Purpose: "queries by id"},
+		Issues: []report.Issue{
+			issue(report.SeverityLow, 5, "close error ignored"),
+			issue(report.SeverityCritical, 4, "SQL injection"),
+			issue(report.SeverityMedium, 3, "already triaged"),
+		},
+	})
+
+	decisions := &triage.Decisions{}
+	decisions.Set("pkg.A", rpt.Units["pkg.A"].Issues[2], triage.WontFix)
+
+	selector, err := newIssueSelector(rpt, nil, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := triageItems(rpt, selector.Select(rpt), decisions, false)
+	var messages []string
+	for _, item := range items {
+		messages = append(messages, item.issue.Message)
+	}
+	if want := []string{"SQL injection", "close error ignored"}; !slices.Equal(messages, want) {
+		t.Fatalf("items = %v, want the untriaged issues, the most severe first", messages)
+	}
+
+	var out bytes.Buffer
+	saves := 0
+	session := &triageSession{
+		rpt:       rpt,
+		items:     items,
+		decisions: decisions,
+		save:      func() error { saves++; return nil },
+		out:       &out,
+	}
+	// Mark the first as false positive, go back, change it, then quit
+	keys := lineKeys{bufio.NewScanner(strings.NewReader("f\np\nt\nq\n"))}
+	if err := session.run(keys); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	entry, ok := decisions.Lookup("pkg.A", *items[0].issue)
+	if !ok || entry.Decision != triage.TruePositive {
+		t.Errorf("decision = %+v, %v, want true positive", entry, ok)
+	}
+	if _, ok := decisions.Lookup("pkg.A", *items[1].issue); ok {
+		t.Error("the skipped issue has a decision")
+	}
+	if saves != 2 || session.decided != 2 {
+		t.Errorf("saves = %d, decided = %d, want 2", saves, session.decided)
+	}
+
+	output := out.String()
+	for _, want := range []string{
+		"[1/2] CRITICAL  security  a.go:4",
+		"Purpose: queries by id",
+		">     4 │     db.Query(\"SELECT \" + id)",
+		"      3 │ func A(db DB, id string) {",
+		"Decision: false positive",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+
+	// Mark the last issue as false positive and undo it, the session stays
+	// on the last issue until quitting
+	session.index, session.decided, session.history = 0, 0, nil
+	keys = lineKeys{bufio.NewScanner(strings.NewReader("n\nf\nu\nq\n"))}
+	if err := session.run(keys); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if _, ok := decisions.Lookup("pkg.A", *items[1].issue); ok {
+		t.Error("the undone decision is still recorded")
+	}
+	if _, ok := decisions.Lookup("pkg.A", *items[0].issue); !ok {
+		t.Error("undo removed the decision about another issue")
+	}
+	if session.index != 1 || session.decided != 0 || saves != 4 {
+		t.Errorf("index = %d, decided = %d, saves = %d, want 1, 0, 4", session.index, session.decided, saves)
+	}
+	if !strings.Contains(out.String(), "Removed the decision about a.go:5") {
+		t.Errorf("output does not report the undo:\n%s", out.String())
+	}
+}
+
+func TestTriageSession_CodeWindow(t *testing.T) {
+	root := t.TempDir()
+	var source strings.Builder
+	source.WriteString("package pkg\n\nfunc Long() {\n")
+	for range 50 {
+		source.WriteString("\tstep()\n")
+	}
+	source.WriteString("}\n")
+	filename := filepath.Join(root, "long.go")
+	if err := os.WriteFile(filename, []byte(source.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	item := &selectedIssue{
+		issue:    &report.Issue{Position: token.Position{Line: 40}},
+		function: report.FunctionInfo{Position: token.Position{Line: 3}, EndLine: 54},
+		filename: filename,
+	}
+	lines := (&triageSession{}).code(item, 9)
+	if len(lines) != 9 || !strings.HasPrefix(lines[4], ">    40") {
+		t.Errorf("code window:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestRawKeys(t *testing.T) {
+	keys := rawKeys{bufio.NewReader(strings.NewReader("T\x1b[D\r\x03"))}
+	var got []string
+	for {
+		key, err := keys.ReadKey()
+		if err != nil {
+			break
+		}
+		got = append(got, key)
+	}
+	if want := []string{"t", "left", "enter", "ctrl-c"}; !slices.Equal(got, want) {
+		t.Errorf("keys = %v, want %v", got, want)
+	}
+}
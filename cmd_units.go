@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/zeebo/clingy"
+
+	"github.com/loov/dreamlint/analyze"
+	"github.com/loov/dreamlint/config"
+	"github.com/loov/dreamlint/extract"
+)
+
+type cmdUnits struct {
+	configPaths   []string
+	inlineConfigs []string
+	format        string
+	sortBy        string
+	allowErrors   bool
+	promptsDir    string
+	patterns      []string
+}
+
+func (c *cmdUnits) Setup(params clingy.Parameters) {
+	c.configPaths = params.Flag("config", "path to config file",
+		[]string{"dreamlint.cue"},
+		clingy.Repeated,
+	).([]string)
+
+	c.inlineConfigs = params.Flag("c", "inline CUE config",
+		[]string{},
+		clingy.Repeated,
+	).([]string)
+
+	c.format = params.Flag("format", "output format: text or json", "text").(string)
+	c.sortBy = params.Flag("sort", "sort by: order, functions, size, callees, or tokens", "order").(string)
+
+	c.allowErrors = params.Flag("allow-errors", "skip packages with errors instead of failing", false,
+		clingy.Transform(strconv.ParseBool), clingy.Boolean,
+	).(bool)
+
+	c.promptsDir = params.Flag("prompts", "directory to load prompts from", "").(string)
+
+	c.patterns = params.Arg("patterns", "packages to analyze",
+		clingy.Optional,
+		clingy.Repeated,
+	).([]string)
+}
+
+// unitStats describes the size and cache status of an analysis unit.
+type unitStats struct {
+	Order     int    `json:"order"`
+	ID        string `json:"id"`
+	Functions int    `json:"functions"`
+	Size      int    `json:"size"`
+	Callees   int    `json:"callees"`
+	External  int    `json:"external"`
+	Tokens    int    `json:"tokens"`
+	Cache     string `json:"cache"`
+}
+
+// Cache statuses of a unit summary.
+const (
+	cacheDisabled = "disabled"
+	cacheHit      = "cached"
+	cacheMiss     = "uncached"
+)
+
+func (c *cmdUnits) Execute(ctx context.Context) error {
+	patterns := c.patterns
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	less, ok := unitsSortOrder[c.sortBy]
+	if !ok {
+		return fmt.Errorf("unknown sort order %q", c.sortBy)
+	}
+
+	cfg, err := config.LoadConfig(c.configPaths, c.inlineConfigs)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	// Progress goes to stderr so the listing can be piped
+	l, err := loadUnits(os.Stderr, cfg, c.allowErrors, ".", patterns)
+	if err != nil {
+		return err
+	}
+
+	pipeline, err := loadPipeline(cfg, nil, l.externalFuncs, c.promptsDir)
+	if err != nil {
+		return err
+	}
+
+	stats, err := collectUnitStats(pipeline, cfg, l.units)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(stats, func(i, k int) bool {
+		return less(stats[i], stats[k])
+	})
+
+	switch c.format {
+	case "text":
+		return writeUnitStatsText(os.Stdout, stats)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	default:
+		return fmt.Errorf("unknown format %q", c.format)
+	}
+}
+
+// unitsSortOrder lists the supported -sort values, larger units first.
+var unitsSortOrder = map[string]func(a, b unitStats) bool{
+	"order":     func(a, b unitStats) bool { return a.Order < b.Order },
+	"functions": func(a, b unitStats) bool { return a.Functions > b.Functions },
+	"size":      func(a, b unitStats) bool { return a.Size > b.Size },
+	"callees":   func(a, b unitStats) bool { return a.Callees > b.Callees },
+	"tokens":    func(a, b unitStats) bool { return a.Tokens > b.Tokens },
+}
+
+// collectUnitStats computes the stats of units in analysis order.
+// Token estimates include the cached summary in the analysis pass prompts
+// when it is available.
+func collectUnitStats(pipeline *analyze.Pipeline, cfg *config.Config, units []*extract.AnalysisUnit) ([]unitStats, error) {
+	summaries := cachedSummaries(pipeline, units, nil)
+
+	stats := make([]unitStats, 0, len(units))
+	for i, unit := range units {
+		s := unitStats{
+			Order:     i + 1,
+			ID:        unit.ID,
+			Functions: len(unit.Functions),
+			Callees:   len(unit.Callees),
+			External:  len(unit.External),
+			Cache:     cacheDisabled,
+		}
+		for _, fn := range unit.Functions {
+			s.Size += len(fn.Body)
+		}
+
+		summary, cached := summaries[unit.ID]
+		if cfg.Cache.Enabled {
+			s.Cache = cacheMiss
+			if cached {
+				s.Cache = cacheHit
+			}
+		}
+
+		prompts, err := pipeline.RenderPrompts(unit, summaries, summary)
+		if err != nil {
+			return nil, err
+		}
+		for _, prompt := range prompts {
+			s.Tokens += analyze.EstimateTokens(prompt.Prompt)
+		}
+
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// writeUnitStatsText writes stats as a table followed by totals.
+func writeUnitStatsText(w io.Writer, stats []unitStats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "ORDER\tFUNCS\tSIZE\tCALLEES\tEXTERNAL\tTOKENS\tCACHE\t\tUNIT")
+
+	var functions, size, tokens, cached int
+	for _, s := range stats {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\t%d\t%s\t\t%s\n",
+			s.Order, s.Functions, s.Size, s.Callees, s.External, s.Tokens, s.Cache, s.ID)
+		functions += s.Functions
+		size += s.Size
+		tokens += s.Tokens
+		if s.Cache == cacheHit {
+			cached++
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d units, %d functions, %d bytes, ~%d prompt tokens, %d cached summaries\n",
+		len(stats), functions, size, tokens, cached)
+	return err
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/parser"
+)
+
+// DefaultAPIKeyEnv lists the environment variables checked for an API key
+// when neither api_key, api_key_file nor api_key_env is set.
+var DefaultAPIKeyEnv = []string{"DREAMLINT_API_KEY", "OPENAI_API_KEY"}
+
+// resolveAPIKeys sets the API key of all LLM configs.
+func (c *Config) resolveAPIKeys() error {
+	if err := c.LLM.resolveAPIKey(); err != nil {
+		return fmt.Errorf("llm: %w", err)
+	}
+	for name, model := range c.Models {
+		if err := model.resolveAPIKey(); err != nil {
+			return fmt.Errorf("models: %s: %w", name, err)
+		}
+		c.Models[name] = model
+	}
+	if err := resolvePassAPIKeys(c.Analyse); err != nil {
+		return err
+	}
+	if c.Fix.LLM != nil {
+		if err := c.Fix.LLM.resolveAPIKey(); err != nil {
+			return fmt.Errorf("fix: llm: %w", err)
+		}
+	}
+	for i := range c.Overrides {
+		override := &c.Overrides[i]
+		if override.LLM != nil {
+			if err := override.LLM.resolveAPIKey(); err != nil {
+				return fmt.Errorf("overrides[%d]: llm: %w", i, err)
+			}
+		}
+		if err := resolvePassAPIKeys(override.Analyse); err != nil {
+			return fmt.Errorf("overrides[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// resolvePassAPIKeys sets the API key of passes with their own LLM config.
+func resolvePassAPIKeys(passes []AnalysisPass) error {
+	for _, pass := range passes {
+		if pass.LLM == nil {
+			continue
+		}
+		if err := pass.LLM.resolveAPIKey(); err != nil {
+			return fmt.Errorf("pass %s: llm: %w", pass.Name, err)
+		}
+	}
+	return nil
+}
+
+// resolveAPIKey sets APIKey from, in order of precedence, the literal api_key,
+// api_key_file, api_key_env or the default environment variables.
+func (c *LLMConfig) resolveAPIKey() error {
+	switch {
+	case c.APIKey != "":
+		return nil
+	case c.APIKeyFile != "":
+		data, err := os.ReadFile(c.APIKeyFile)
+		if err != nil {
+			return fmt.Errorf("read api_key_file: %w", err)
+		}
+		c.APIKey = strings.TrimSpace(string(data))
+		if c.APIKey == "" {
+			return fmt.Errorf("api_key_file %s is empty", c.APIKeyFile)
+		}
+	case c.APIKeyEnv != "":
+		c.APIKey = os.Getenv(c.APIKeyEnv)
+		if c.APIKey == "" {
+			return fmt.Errorf("api_key_env: environment variable %s is not set", c.APIKeyEnv)
+		}
+	default:
+		for _, env := range DefaultAPIKeyEnv {
+			if key := os.Getenv(env); key != "" {
+				c.APIKey = key
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// String formats the config with the API key redacted,
+// so that the key does not end up in logs.
+func (c LLMConfig) String() string {
+	type plain LLMConfig
+	return fmt.Sprintf("%+v", plain(c.redacted()))
+}
+
+// RedactSource replaces the values of api_key fields in CUE source, such as
+// an inline config. When the source cannot be parsed or a key may be defined
+// elsewhere, e.g. api_key refers to another field, the whole source is
+// replaced.
+func RedactSource(src string) string {
+	file, err := parser.ParseFile("inline", src)
+	if err != nil {
+		if strings.Contains(src, "api_key") {
+			return redacted
+		}
+		return src
+	}
+
+	var values []ast.Expr
+	literal := true
+	ast.Walk(file, func(node ast.Node) bool {
+		field, ok := node.(*ast.Field)
+		if !ok {
+			return true
+		}
+		if name, _, err := ast.LabelName(field.Label); err != nil || name != "api_key" {
+			return true
+		}
+		values = append(values, field.Value)
+		literal = literal && literalValue(field.Value)
+		return false
+	}, nil)
+	if !literal {
+		return redacted
+	}
+
+	// Replace from the end, so that the offsets stay valid
+	for i := len(values) - 1; i >= 0; i-- {
+		start, end := values[i].Pos().Offset(), values[i].End().Offset()
+		src = src[:start] + strconv.Quote(redacted) + src[end:]
+	}
+	return src
+}
+
+// literalValue reports whether x consists only of literals and types,
+// such as string | *"sk-...".
+func literalValue(x ast.Expr) bool {
+	switch x := x.(type) {
+	case *ast.BasicLit:
+		return true
+	case *ast.Ident:
+		return x.Name == "string" || x.Name == "_"
+	case *ast.UnaryExpr:
+		return literalValue(x.X)
+	case *ast.BinaryExpr:
+		return literalValue(x.X) && literalValue(x.Y)
+	case *ast.ParenExpr:
+		return literalValue(x.X)
+	default:
+		return false
+	}
+}
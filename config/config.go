@@ -2,12 +2,15 @@ package config
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
 	"os"
 
 	"cuelang.org/go/cue"
 	"cuelang.org/go/cue/cuecontext"
 	"cuelang.org/go/cue/load"
+
+	"github.com/loov/dreamlint/report"
 )
 
 //go:embed schema.cue
@@ -15,20 +18,60 @@ var schemaCue string
 
 // Config is the main configuration structure
 type Config struct {
-	LLM     LLMConfig      `json:"llm"`
-	Cache   CacheConfig    `json:"cache"`
-	Output  OutputConfig   `json:"output"`
-	Analyse []AnalysisPass `json:"analyse"`
+	LLM       LLMConfig            `json:"llm"`
+	Cache     CacheConfig          `json:"cache"`
+	Output    OutputConfig         `json:"output"`
+	JUnit     JUnitConfig          `json:"junit"`
+	Triage    TriageConfig         `json:"triage"`
+	Examples  ExamplesConfig       `json:"examples"`
+	Packages  PackagesConfig       `json:"packages"`
+	Filter    FilterConfig         `json:"filter"`
+	Models    map[string]LLMConfig `json:"models,omitempty"`
+	Analyse   []AnalysisPass       `json:"analyse"`
+	Overrides []Override           `json:"overrides,omitempty"`
+	Fix       FixConfig            `json:"fix"`
+	Knowledge map[string]Knowledge `json:"knowledge,omitempty"`
+
+	// Severities configures issue severities, empty uses report.DefaultSeverities.
+	Severities report.Severities `json:"severities,omitempty"`
+}
+
+// IssueSeverities returns the configured severities or report.DefaultSeverities.
+func (c *Config) IssueSeverities() report.Severities {
+	if len(c.Severities) > 0 {
+		return c.Severities
+	}
+	return report.DefaultSeverities
+}
+
+// checkSeverities reports minimum severities that are not issue severities.
+func (c *Config) checkSeverities() error {
+	check := func(field, severity string) error {
+		if severity == "" {
+			return nil
+		}
+		if _, ok := c.IssueSeverities().Level(report.Severity(severity)); !ok {
+			return fmt.Errorf("%s: unknown severity %q", field, severity)
+		}
+		return nil
+	}
+	return errors.Join(
+		check("fix.min_severity", c.Fix.MinSeverity),
+		check("junit.min_severity", c.JUnit.MinSeverity),
+	)
 }
 
 // LLMConfig holds LLM connection settings
 type LLMConfig struct {
-	Provider    string  `json:"provider"`
-	BaseURL     string  `json:"base_url"`
-	Model       string  `json:"model"`
-	APIKey      string  `json:"api_key,omitempty"`
-	MaxTokens   int     `json:"max_tokens"`
-	Temperature float64 `json:"temperature"`
+	Provider      string  `json:"provider"`
+	BaseURL       string  `json:"base_url"`
+	Model         string  `json:"model"`
+	APIKey        string  `json:"api_key,omitempty"`
+	APIKeyEnv     string  `json:"api_key_env,omitempty"`
+	APIKeyFile    string  `json:"api_key_file,omitempty"`
+	MaxTokens     int     `json:"max_tokens"`
+	Temperature   float64 `json:"temperature"`
+	ContextWindow int     `json:"context_window,omitempty"`
 }
 
 // CacheConfig holds cache settings
@@ -42,14 +85,67 @@ type OutputConfig struct {
 	JSON     string `json:"json"`
 	Markdown string `json:"markdown"`
 	SARIF    string `json:"sarif"`
+	JUnit    string `json:"junit"`
+}
+
+// JUnitConfig holds JUnit report settings
+type JUnitConfig struct {
+	// MinSeverity is the least severe issue reported as a failure, empty
+	// reports all issues as failures.
+	MinSeverity string `json:"min_severity,omitempty"`
+	// TestCase is what a test case is: "unit" or "package".
+	TestCase string `json:"testcase"`
+}
+
+// TriageConfig holds triage settings
+type TriageConfig struct {
+	// File stores the triage decisions, empty disables triage.
+	File string `json:"file"`
+}
+
+// ExamplesConfig holds few-shot example settings
+type ExamplesConfig struct {
+	// Max is the number of triaged issues included in each analysis pass prompt.
+	Max int `json:"max"`
+}
+
+// PackagesConfig holds package loading settings
+type PackagesConfig struct {
+	Tests     bool     `json:"tests"`
+	BuildTags []string `json:"build_tags"`
+	GOOS      string   `json:"goos"`
+	GOARCH    string   `json:"goarch"`
+}
+
+// FilterConfig selects which functions are analyzed
+type FilterConfig struct {
+	Packages  Patterns `json:"packages"`
+	Files     Patterns `json:"files"`
+	Functions Patterns `json:"functions"`
+	Generated bool     `json:"generated"`
+}
+
+// Patterns holds include and exclude patterns
+type Patterns struct {
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
 }
 
 // AnalysisPass defines a single analysis pass
 type AnalysisPass struct {
-	Name    string     `json:"name"`
-	Prompt  string     `json:"prompt"`
-	Enabled bool       `json:"enabled"`
-	LLM     *LLMConfig `json:"llm,omitempty"`
+	Name        string     `json:"name"`
+	Prompt      string     `json:"prompt"`
+	Description string     `json:"description,omitempty"`
+	Enabled     bool       `json:"enabled"`
+	LLM         *LLMConfig `json:"llm,omitempty"`
+	// Models lists names of Config.Models to try in order, when llm refers to profiles.
+	Models []string `json:"-"`
+}
+
+// Knowledge holds known invariants and pitfalls of an external function
+type Knowledge struct {
+	Invariants []string `json:"invariants,omitempty"`
+	Pitfalls   []string `json:"pitfalls,omitempty"`
 }
 
 // LoadConfig loads and validates Cue configuration from multiple files and inline strings.
@@ -116,5 +212,14 @@ func LoadConfig(paths []string, inlineConfigs []string) (*Config, error) {
 	if err := unified.Decode(&cfg); err != nil {
 		return nil, fmt.Errorf("decode config: %w", err)
 	}
+	if err := cfg.checkModels(); err != nil {
+		return nil, err
+	}
+	if err := cfg.checkSeverities(); err != nil {
+		return nil, err
+	}
+	if err := cfg.resolveAPIKeys(); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
@@ -1,6 +1,10 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -132,3 +136,188 @@ func TestLoadConfigInline(t *testing.T) {
 		t.Errorf("model = %s, want claude-3", cfg.LLM.Model)
 	}
 }
+
+func TestLoadConfigFix(t *testing.T) {
+	cfg, err := LoadConfig(
+		[]string{"./testdata/base.cue"},
+		[]string{`fix: {enabled: true, min_severity: "high", llm: {base_url: "http://localhost:8080/v1", model: "fixer"}}`},
+	)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if !cfg.Fix.Enabled || cfg.Fix.Prompt != "builtin:fix" || cfg.Fix.MinSeverity != "high" {
+		t.Errorf("fix = %+v", cfg.Fix)
+	}
+	pass := cfg.Fix.Pass()
+	if pass.LLM == nil || pass.LLM.Model != "fixer" {
+		t.Errorf("fix pass LLM = %+v", pass.LLM)
+	}
+}
+
+func TestLoadConfigUnknownSeverity(t *testing.T) {
+	tests := []struct {
+		name   string
+		inline string
+		error  string
+	}{
+		{"fix", `fix: {enabled: true, min_severity: "urgent"}`, `fix.min_severity: unknown severity "urgent"`},
+		{"junit", `junit: min_severity: "urgent"`, `junit.min_severity: unknown severity "urgent"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := LoadConfig([]string{"./testdata/base.cue"}, []string{test.inline})
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				t.Errorf("LoadConfig error = %v, want %q", err, test.error)
+			}
+		})
+	}
+}
+
+func TestLoadConfigKnowledge(t *testing.T) {
+	cfg, err := LoadConfig([]string{
+		"./testdata/base.cue",
+		"./testdata/knowledge.cue",
+	}, nil)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if len(cfg.Knowledge) != 2 {
+		t.Fatalf("knowledge count = %d, want 2", len(cfg.Knowledge))
+	}
+
+	query := cfg.Knowledge["example.com/db.(*Conn).Query"]
+	if len(query.Pitfalls) != 1 || query.Pitfalls[0] != "rows must be closed" {
+		t.Errorf("pitfalls = %v, want [rows must be closed]", query.Pitfalls)
+	}
+	if len(query.Invariants) != 0 {
+		t.Errorf("invariants = %v, want []", query.Invariants)
+	}
+}
+
+func TestLoadConfigFilter(t *testing.T) {
+	cfg, err := LoadConfig([]string{
+		"./testdata/base.cue",
+		"./testdata/filter.cue",
+	}, nil)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if len(cfg.Filter.Packages.Exclude) != 1 {
+		t.Errorf("filter.packages.exclude = %v, want 1 pattern", cfg.Filter.Packages.Exclude)
+	}
+	if len(cfg.Filter.Files.Exclude) != 2 {
+		t.Errorf("filter.files.exclude = %v, want 2 patterns", cfg.Filter.Files.Exclude)
+	}
+	if len(cfg.Filter.Functions.Include) != 0 {
+		t.Errorf("filter.functions.include = %v, want empty", cfg.Filter.Functions.Include)
+	}
+	if cfg.Filter.Generated {
+		t.Error("filter.generated should default to false")
+	}
+}
+
+func TestLoadConfigPackages(t *testing.T) {
+	cfg, err := LoadConfig(
+		[]string{"./testdata/base.cue"},
+		[]string{`packages: { tests: true, build_tags: ["integration"], goos: "linux" }`},
+	)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if !cfg.Packages.Tests {
+		t.Error("packages.tests should be true")
+	}
+	if len(cfg.Packages.BuildTags) != 1 || cfg.Packages.BuildTags[0] != "integration" {
+		t.Errorf("packages.build_tags = %v, want [integration]", cfg.Packages.BuildTags)
+	}
+	if cfg.Packages.GOOS != "linux" {
+		t.Errorf("packages.goos = %q, want linux", cfg.Packages.GOOS)
+	}
+	if cfg.Packages.GOARCH != "" {
+		t.Errorf("packages.goarch = %q, want empty", cfg.Packages.GOARCH)
+	}
+}
+
+func TestLoadConfigModels(t *testing.T) {
+	cfg, err := LoadConfig([]string{"./testdata/models.cue"}, nil)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	models := make(map[string][]string)
+	for _, pass := range cfg.Analyse {
+		for _, llm := range cfg.PassLLMs(pass) {
+			models[pass.Name] = append(models[pass.Name], llm.Model)
+		}
+	}
+	want := map[string][]string{
+		"summary":     {"small"},
+		"security":    {"small", "large"},
+		"correctness": {"default"},
+	}
+	for name, chain := range want {
+		if !slices.Equal(models[name], chain) {
+			t.Errorf("%s models = %v, want %v", name, models[name], chain)
+		}
+	}
+
+	// The formatted config refers to the same profiles
+	data, err := cfg.FormatCUE()
+	if err != nil {
+		t.Fatalf("FormatCUE: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "resolved.cue")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadConfig([]string{path}, nil)
+	if err != nil {
+		t.Fatalf("LoadConfig(formatted): %v\n%s", err, data)
+	}
+	for i, pass := range loaded.Analyse {
+		if !slices.Equal(pass.Models, cfg.Analyse[i].Models) {
+			t.Errorf("formatted %s models = %v, want %v", pass.Name, pass.Models, cfg.Analyse[i].Models)
+		}
+	}
+
+	_, err = LoadConfig([]string{"./testdata/models.cue"}, []string{`pass: maintainability: {prompt: "builtin:maintainability", llm: "missing"}`})
+	if err == nil || !strings.Contains(err.Error(), `"missing"`) {
+		t.Errorf("LoadConfig with undefined profile: got %v, want error mentioning the profile", err)
+	}
+}
+
+func TestLoadConfigOverrides(t *testing.T) {
+	cfg, err := LoadConfig([]string{"./testdata/overrides.cue"}, nil)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+	if len(cfg.Overrides) != 2 {
+		t.Fatalf("got %d overrides, want 2", len(cfg.Overrides))
+	}
+	if want := []string{"internal/auth/...", "api/..."}; !slices.Equal(cfg.Overrides[0].Match, want) {
+		t.Errorf("overrides[0].match = %v, want %v", cfg.Overrides[0].Match, want)
+	}
+	if want := []string{"example.com/app/gen/..."}; !slices.Equal(cfg.Overrides[1].Match, want) {
+		t.Errorf("overrides[1].match = %v, want %v", cfg.Overrides[1].Match, want)
+	}
+
+	// Later overrides take precedence, the llm of earlier ones still applies
+	passes := cfg.Passes(cfg.Overrides)
+	if len(passes) != 1 || passes[0].Name != "summary" {
+		t.Fatalf("passes = %v, want only summary", passes)
+	}
+	if llms := cfg.PassLLMs(passes[0]); len(llms) != 1 || llms[0].Model != "strong" {
+		t.Errorf("summary models = %v, want [strong]", llms)
+	}
+	if passes := cfg.Passes(nil); len(passes) != 2 || cfg.PassLLMs(passes[0])[0].Model != "default" {
+		t.Errorf("passes without overrides changed: %v", passes)
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+import "encoding/json"
+
+// FixConfig configures generating code fixes for issues
+type FixConfig struct {
+	Enabled bool   `json:"enabled"`
+	Prompt  string `json:"prompt"`
+	// MinSeverity is the least severe issue to fix, empty fixes all issues.
+	MinSeverity string `json:"min_severity,omitempty"`
+	// LLM and Models configure the LLM, as in AnalysisPass.
+	LLM    *LLMConfig `json:"-"`
+	Models []string   `json:"-"`
+}
+
+// UnmarshalJSON decodes the fix configuration, where llm is the same as in AnalysisPass.
+func (f *FixConfig) UnmarshalJSON(data []byte) error {
+	type plain FixConfig
+	var aux struct {
+		plain
+		LLM json.RawMessage `json:"llm,omitempty"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*f = FixConfig(aux.plain)
+
+	var err error
+	f.LLM, f.Models, err = decodeLLM(aux.LLM)
+	return err
+}
+
+// MarshalJSON encodes the fix configuration in the same form as UnmarshalJSON accepts.
+func (f FixConfig) MarshalJSON() ([]byte, error) {
+	type plain FixConfig
+	return json.Marshal(struct {
+		plain
+		LLM any `json:"llm,omitempty"`
+	}{
+		plain: plain(f),
+		LLM:   encodeLLM(f.LLM, f.Models),
+	})
+}
+
+// Pass returns the fix generation as a pass, e.g. for PassLLMs.
+func (f FixConfig) Pass() AnalysisPass {
+	return AnalysisPass{
+		Name:    "fix",
+		Prompt:  f.Prompt,
+		Enabled: f.Enabled,
+		LLM:     f.LLM,
+		Models:  f.Models,
+	}
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/cuecontext"
+	"cuelang.org/go/cue/format"
+
+	"github.com/loov/dreamlint/report"
+)
+
+// Schema returns the CUE schema that configuration files are unified with.
+func Schema() string {
+	return schemaCue
+}
+
+// redacted replaces secrets when printing the config.
+const redacted = "<redacted>"
+
+// Redacted returns a copy of the config with API keys replaced.
+func (c *Config) Redacted() *Config {
+	r := *c
+	r.LLM = c.LLM.redacted()
+	if c.Models != nil {
+		r.Models = make(map[string]LLMConfig, len(c.Models))
+		for name, model := range c.Models {
+			r.Models[name] = model.redacted()
+		}
+	}
+	r.Analyse = redactPasses(c.Analyse)
+	if c.Fix.LLM != nil {
+		llm := c.Fix.LLM.redacted()
+		r.Fix.LLM = &llm
+	}
+	r.Overrides = slices.Clone(c.Overrides)
+	for i, override := range r.Overrides {
+		if override.LLM != nil {
+			llm := override.LLM.redacted()
+			r.Overrides[i].LLM = &llm
+		}
+		r.Overrides[i].Analyse = redactPasses(override.Analyse)
+	}
+	return &r
+}
+
+// redactPasses returns a copy of passes with API keys replaced.
+func redactPasses(passes []AnalysisPass) []AnalysisPass {
+	passes = slices.Clone(passes)
+	for i, pass := range passes {
+		if pass.LLM != nil {
+			llm := pass.LLM.redacted()
+			passes[i].LLM = &llm
+		}
+	}
+	return passes
+}
+
+func (c LLMConfig) redacted() LLMConfig {
+	if c.APIKey != "" {
+		c.APIKey = redacted
+	}
+	return c
+}
+
+// FormatCUE formats the config as a CUE file.
+func (c *Config) FormatCUE() ([]byte, error) {
+	v := cuecontext.New().Encode(c)
+	if v.Err() != nil {
+		return nil, fmt.Errorf("encode config: %w", v.Err())
+	}
+
+	file := &ast.File{Decls: []ast.Decl{&ast.Package{Name: ast.NewIdent("config")}}}
+	switch node := v.Syntax(cue.Final(), cue.Concrete(true)).(type) {
+	case *ast.StructLit:
+		file.Decls = append(file.Decls, node.Elts...)
+	case *ast.File:
+		file.Decls = append(file.Decls, node.Decls...)
+	default:
+		return nil, fmt.Errorf("encode config: unexpected %T", node)
+	}
+	return format.Node(file)
+}
+
+// Validate checks the parts of the config that the schema does not.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.LLM.BaseURL == "" {
+		errs = append(errs, errors.New("llm.base_url is empty"))
+	}
+	if c.LLM.Model == "" {
+		errs = append(errs, errors.New("llm.model is empty"))
+	}
+
+	severities := make(map[report.Severity]bool)
+	for _, level := range c.Severities {
+		if level.Name == "" {
+			errs = append(errs, errors.New("severity name is empty"))
+		} else if severities[level.Name] {
+			errs = append(errs, fmt.Errorf("severity %q is listed more than once", level.Name))
+		}
+		severities[level.Name] = true
+	}
+
+	errs = append(errs, validatePasses("analyse", c.Analyse)...)
+	for i, override := range c.Overrides {
+		if len(override.Analyse) > 0 {
+			errs = append(errs, validatePasses(fmt.Sprintf("overrides[%d].analyse", i), override.Analyse)...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validatePasses checks that pass names are unique and that an enabled summary pass is present.
+func validatePasses(field string, passes []AnalysisPass) []error {
+	var errs []error
+	seen := make(map[string]bool)
+	summary := false
+	for _, pass := range passes {
+		if seen[pass.Name] {
+			errs = append(errs, fmt.Errorf("%s: analysis pass %q is listed more than once", field, pass.Name))
+		}
+		seen[pass.Name] = true
+		if pass.Name == "summary" && pass.Enabled {
+			summary = true
+		}
+	}
+	if !summary {
+		errs = append(errs, fmt.Errorf(`%s does not include an enabled "summary" pass, other passes depend on it`, field))
+	}
+	return errs
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestConfig_Redacted(t *testing.T) {
+	cfg, err := LoadConfig([]string{"./testdata/base.cue"}, []string{
+		`llm: api_key: "secret"`,
+		`analyse: [{name: "summary", prompt: "builtin:summary", llm: {base_url: "http://other/v1", model: "m", api_key: "other-secret"}}]`,
+	})
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	r := cfg.Redacted()
+	if r.LLM.APIKey != redacted || r.Analyse[0].LLM.APIKey != redacted {
+		t.Errorf("API keys not redacted: %q, %q", r.LLM.APIKey, r.Analyse[0].LLM.APIKey)
+	}
+	if cfg.LLM.APIKey != "secret" || cfg.Analyse[0].LLM.APIKey != "other-secret" {
+		t.Errorf("Redacted modified the original config")
+	}
+
+	data, err := r.FormatCUE()
+	if err != nil {
+		t.Fatalf("FormatCUE: %v", err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("formatted config contains a secret:\n%s", data)
+	}
+
+	// The formatted config must load back to the same values
+	path := t.TempDir() + "/resolved.cue"
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadConfig([]string{path}, nil)
+	if err != nil {
+		t.Fatalf("LoadConfig(formatted): %v\n%s", err, data)
+	}
+	if loaded.LLM.Model != "llama3" || len(loaded.Analyse) != 1 || loaded.Analyse[0].LLM.Model != "m" {
+		t.Errorf("formatted config loaded differently:\n%s", data)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		inline []string
+		errors []string
+	}{
+		{"valid", nil, nil},
+		{"no summary", []string{`analyse: [{name: "security", prompt: "builtin:security"}]`},
+			[]string{`"summary" pass`}},
+		{"disabled summary", []string{`analyse: [{name: "summary", prompt: "builtin:summary", enabled: false}]`},
+			[]string{`"summary" pass`}},
+		{"duplicate", []string{`analyse: [{name: "summary", prompt: "a"}, {name: "summary", prompt: "b"}]`},
+			[]string{`"summary" is listed more than once`}},
+		{"empty model", []string{`llm: model: ""`}, []string{"llm.model is empty"}},
+		{"override without summary", []string{`overrides: [{match: "pkg/...", analyse: [{name: "security", prompt: "builtin:security"}]}]`},
+			[]string{`overrides[0].analyse does not include an enabled "summary" pass`}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			paths := []string{"./testdata/simple.cue"}
+			if len(test.inline) > 0 {
+				// simple.cue lists its own passes
+				paths = []string{"./testdata/base.cue"}
+			}
+			cfg, err := LoadConfig(paths, test.inline)
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+
+			err = cfg.Validate()
+			if len(test.errors) == 0 {
+				if err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate succeeded, want errors %q", test.errors)
+			}
+			for _, want := range test.errors {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate error %q does not contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfig_APIKey(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := dir + "/key"
+	if err := os.WriteFile(keyFile, []byte("file-key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("DREAMLINT_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "openai-key")
+	t.Setenv("CUSTOM_KEY", "custom-key")
+
+	tests := []struct {
+		name   string
+		inline string
+		want   string
+	}{
+		{"default env", ``, "openai-key"},
+		{"literal", `llm: api_key: "literal-key"`, "literal-key"},
+		{"env", `llm: api_key_env: "CUSTOM_KEY"`, "custom-key"},
+		{"file", `llm: api_key_file: "` + keyFile + `"`, "file-key"},
+		{"file before env", `llm: {api_key_file: "` + keyFile + `", api_key_env: "CUSTOM_KEY"}`, "file-key"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := LoadConfig([]string{"./testdata/base.cue"}, []string{test.inline})
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.LLM.APIKey != test.want {
+				t.Errorf("api key = %q, want %q", cfg.LLM.APIKey, test.want)
+			}
+			if s := cfg.LLM.String(); strings.Contains(s, test.want) {
+				t.Errorf("String() contains the API key: %s", s)
+			}
+		})
+	}
+
+	t.Setenv("DREAMLINT_API_KEY", "dreamlint-key")
+	cfg, err := LoadConfig([]string{"./testdata/base.cue"}, []string{
+		`analyse: [{name: "summary", prompt: "builtin:summary", llm: {base_url: "http://other/v1", model: "m"}}]`,
+	})
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.LLM.APIKey != "dreamlint-key" || cfg.Analyse[0].LLM.APIKey != "dreamlint-key" {
+		t.Errorf("api keys = %q, %q, want DREAMLINT_API_KEY for both", cfg.LLM.APIKey, cfg.Analyse[0].LLM.APIKey)
+	}
+
+	for _, inline := range []string{
+		`llm: api_key_env: "MISSING_KEY_VARIABLE"`,
+		`llm: api_key_file: "` + dir + `/missing"`,
+	} {
+		if _, err := LoadConfig([]string{"./testdata/base.cue"}, []string{inline}); err == nil {
+			t.Errorf("LoadConfig(%s) succeeded, want error", inline)
+		}
+	}
+}
+
+func TestRedactSource(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{`llm: api_key: "sk-123"`, `llm: api_key: "<redacted>"`},
+		{`llm: {api_key:"a\"b", model: "m"}`, `llm: {api_key:"<redacted>", model: "m"}`},
+		{`llm: api_key_env: "KEY"`, `llm: api_key_env: "KEY"`},
+		{`llm: model: "m"`, `llm: model: "m"`},
+		{`llm: "api_key": "sk-123"`, `llm: "api_key": "<redacted>"`},
+		{`llm: api_key: string | *"sk-123"`, `llm: api_key: "<redacted>"`},
+		{`models: a: {api_key: "sk-1"}, models: b: {api_key: 'sk-2'}`, `models: a: {api_key: "<redacted>"}, models: b: {api_key: "<redacted>"}`},
+		{`key: "sk-123", llm: api_key: key`, `<redacted>`},
+		{`llm: api_key: "sk-123`, `<redacted>`},
+	}
+	for _, test := range tests {
+		if got := RedactSource(test.src); got != test.want {
+			t.Errorf("RedactSource(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// UnmarshalJSON decodes a pass, where llm is either a configuration,
+// a profile name or a list of profile names.
+func (p *AnalysisPass) UnmarshalJSON(data []byte) error {
+	type plain AnalysisPass
+	var aux struct {
+		plain
+		LLM json.RawMessage `json:"llm,omitempty"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*p = AnalysisPass(aux.plain)
+
+	var err error
+	p.LLM, p.Models, err = decodeLLM(aux.LLM)
+	return err
+}
+
+// MarshalJSON encodes a pass in the same form as UnmarshalJSON accepts.
+func (p AnalysisPass) MarshalJSON() ([]byte, error) {
+	type plain AnalysisPass
+	return json.Marshal(struct {
+		plain
+		LLM any `json:"llm,omitempty"`
+	}{
+		plain: plain(p),
+		LLM:   encodeLLM(p.LLM, p.Models),
+	})
+}
+
+// decodeLLM decodes an llm field that is either a configuration,
+// a profile name or a list of profile names.
+func decodeLLM(data json.RawMessage) (*LLMConfig, []string, error) {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0 || bytes.Equal(data, []byte("null")):
+		return nil, nil, nil
+	case data[0] == '"':
+		var name string
+		if err := json.Unmarshal(data, &name); err != nil {
+			return nil, nil, err
+		}
+		return nil, []string{name}, nil
+	case data[0] == '[':
+		var names []string
+		if err := json.Unmarshal(data, &names); err != nil {
+			return nil, nil, err
+		}
+		return nil, names, nil
+	default:
+		llm := new(LLMConfig)
+		if err := json.Unmarshal(data, llm); err != nil {
+			return nil, nil, err
+		}
+		return llm, nil, nil
+	}
+}
+
+// encodeLLM returns the value of an llm field in the form decodeLLM accepts.
+func encodeLLM(llm *LLMConfig, models []string) any {
+	switch {
+	case llm != nil:
+		return llm
+	case len(models) == 1:
+		return models[0]
+	case len(models) > 1:
+		return models
+	}
+	return nil
+}
+
+// checkModels reports LLM references to undefined profiles.
+func (c *Config) checkModels() error {
+	check := func(where string, models []string) error {
+		for _, name := range models {
+			if _, ok := c.Models[name]; !ok {
+				return fmt.Errorf("%s: llm: model profile %q is not defined in models", where, name)
+			}
+		}
+		return nil
+	}
+
+	for _, pass := range c.Analyse {
+		if err := check("pass "+pass.Name, pass.Models); err != nil {
+			return err
+		}
+	}
+	if err := check("fix", c.Fix.Models); err != nil {
+		return err
+	}
+	for i, override := range c.Overrides {
+		where := fmt.Sprintf("overrides[%d]", i)
+		if err := check(where, override.Models); err != nil {
+			return err
+		}
+		for _, pass := range override.Analyse {
+			if err := check(where+": pass "+pass.Name, pass.Models); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// PassLLMs returns the LLM configurations for pass, in the order they should be tried.
+// Passes without their own llm use the top-level llm.
+func (c *Config) PassLLMs(pass AnalysisPass) []LLMConfig {
+	switch {
+	case pass.LLM != nil:
+		return []LLMConfig{*pass.LLM}
+	case len(pass.Models) > 0:
+		chain := make([]LLMConfig, 0, len(pass.Models))
+		for _, name := range pass.Models {
+			chain = append(chain, c.Models[name])
+		}
+		return chain
+	default:
+		return []LLMConfig{c.LLM}
+	}
+}
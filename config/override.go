@@ -0,0 +1,92 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+)
+
+// Override changes the analysis of units with functions matching a pattern
+type Override struct {
+	// Match lists patterns matched against package import paths and
+	// file paths relative to the working directory.
+	Match []string `json:"match"`
+	// Analyse replaces the passes to run, when not empty.
+	Analyse []AnalysisPass `json:"analyse,omitempty"`
+	// LLM and Models replace the LLM of every pass, as in AnalysisPass.
+	LLM    *LLMConfig `json:"-"`
+	Models []string   `json:"-"`
+}
+
+// UnmarshalJSON decodes an override, where match is either a pattern or a list
+// of patterns and llm is the same as in AnalysisPass.
+func (o *Override) UnmarshalJSON(data []byte) error {
+	type plain Override
+	var aux struct {
+		plain
+		Match json.RawMessage `json:"match"`
+		LLM   json.RawMessage `json:"llm,omitempty"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*o = Override(aux.plain)
+
+	match := bytes.TrimSpace(aux.Match)
+	if len(match) > 0 && match[0] == '"' {
+		o.Match = []string{""}
+		if err := json.Unmarshal(match, &o.Match[0]); err != nil {
+			return err
+		}
+	} else if err := json.Unmarshal(match, &o.Match); err != nil {
+		return err
+	}
+
+	var err error
+	o.LLM, o.Models, err = decodeLLM(aux.LLM)
+	return err
+}
+
+// MarshalJSON encodes an override in the same form as UnmarshalJSON accepts.
+func (o Override) MarshalJSON() ([]byte, error) {
+	type plain Override
+	return json.Marshal(struct {
+		plain
+		LLM any `json:"llm,omitempty"`
+	}{
+		plain: plain(o),
+		LLM:   encodeLLM(o.LLM, o.Models),
+	})
+}
+
+// Passes returns the passes to run for a unit, given the overrides that match it in order.
+// Later overrides take precedence.
+func (c *Config) Passes(overrides []Override) []AnalysisPass {
+	passes := c.Analyse
+	for _, override := range overrides {
+		if len(override.Analyse) > 0 {
+			passes = override.Analyse
+		}
+	}
+	passes = slices.Clone(passes)
+
+	for _, override := range overrides {
+		if override.LLM == nil && len(override.Models) == 0 {
+			continue
+		}
+		for i := range passes {
+			passes[i].LLM = override.LLM
+			passes[i].Models = override.Models
+		}
+	}
+	return passes
+}
+
+// AllPasses returns the passes of analyse and of all overrides.
+func (c *Config) AllPasses() []AnalysisPass {
+	passes := slices.Clone(c.Analyse)
+	for _, override := range c.Overrides {
+		passes = append(passes, override.Analyse...)
+	}
+	return passes
+}
@@ -10,10 +10,17 @@ package config
 	model: string
 	// api_key specifies the API key for the Language Model provider.
 	api_key?: string
+	// api_key_env specifies the environment variable holding the API key.
+	api_key_env?: string
+	// api_key_file specifies a file containing the API key, relative to the working directory.
+	api_key_file?: string
 	// max_tokens specifies the maximum number of tokens to be used by the Language Model.
 	max_tokens: int | *4096
 	// temperature specifies the temperature to be used by the Language Model.
 	temperature: float | *0.1
+	// context_window specifies the maximum prompt size in tokens, prompts estimated
+	// to be larger move on to the next model of a fallback chain. Zero means unknown.
+	context_window: int | *0
 }
 
 // AnalysisPass represents the configuration for an analysis pass.
@@ -27,7 +34,51 @@ package config
 	// enabled specifies whether the analysis pass is enabled.
 	enabled: bool | *true
 	// llm allows overriding the configuration for the Language Model to be used by the analysis pass.
-	llm?: #LLMConfig
+	// It is either a configuration, the name of a profile in models, or a list
+	// of profile names that are tried in order when a model fails.
+	llm?: #LLMConfig | string | [string, ...string]
+}
+
+// Knowledge represents known facts about an external function.
+#Knowledge: {
+	// invariants lists guarantees and preconditions of the function.
+	invariants: [...string] | *[]
+	// pitfalls lists common mistakes made when calling the function.
+	pitfalls: [...string] | *[]
+}
+
+// Patterns represents include and exclude patterns.
+//
+// Patterns are globs where "*" matches any sequence except "/" and "..."
+// matches any sequence, e.g. "example.com/app/internal/...".
+// Patterns enclosed in slashes are regular expressions, e.g. "/_string\\.go$/".
+#Patterns: {
+	// include lists patterns to analyze, empty includes everything.
+	include: [...string] | *[]
+	// exclude lists patterns to skip.
+	exclude: [...string] | *[]
+}
+
+// Severity describes an issue severity.
+#Severity: {
+	// name specifies the name used by the LLM and in reports, e.g. "critical".
+	name: string
+	// description explains when the severity applies, it is included in prompts.
+	description: string | *""
+	// sarif specifies the SARIF result level of the severity.
+	sarif: "error" | "warning" | "note" | "none"
+}
+
+// Override changes the analysis of units with functions matching a pattern.
+#Override: {
+	// match specifies patterns matched against package import paths and file
+	// paths relative to the working directory, e.g. "example.com/app/api/..."
+	// or "internal/auth/...". The pattern syntax is the same as in #Patterns.
+	match: string | [string, ...string]
+	// analyse replaces the passes to run.
+	analyse?: [...#AnalysisPass]
+	// llm replaces the Language Model of every pass, in the same forms as #AnalysisPass.llm.
+	llm?: #LLMConfig | string | [string, ...string]
 }
 
 // Config represents the configuration for the tool.
@@ -41,12 +92,94 @@ package config
 		json:     string | *"dreamlint-report.json"
 		markdown: string | *"dreamlint-report.md"
 		sarif:    string | *"dreamlint-report.sarif"
+		junit:    string | *"dreamlint-report.junit.xml"
+	}
+
+	// junit configures the JUnit XML report.
+	junit: {
+		// min_severity specifies the least severe issues reported as test
+		// failures, empty reports all issues as failures. Less severe issues
+		// are listed in the output of the test case.
+		min_severity: string | *""
+		// testcase specifies whether every analysis unit or every package is a test case.
+		testcase: *"unit" | "package"
+	}
+
+	// triage configures the decisions made with `dreamlint triage`.
+	triage: {
+		// file stores the decisions, which later runs use to suppress false
+		// positives and carry forward confirmed issues. Empty disables triage.
+		file: string | *"dreamlint-triage.json"
+	}
+
+	// examples configures few-shot examples taken from the triage decisions.
+	examples: {
+		// max is the number of confirmed issues and false positives of a pass
+		// included in its prompt, the most similar to the analyzed code first.
+		// Zero disables examples.
+		max: int & >=0 | *3
+	}
+
+	// packages configures how Go packages are loaded.
+	packages: {
+		// tests specifies whether to analyze _test.go files and external test packages.
+		tests: bool | *false
+		// build_tags specifies build tags used when loading packages, e.g. ["integration"].
+		build_tags: [...string] | *[]
+		// goos specifies the target operating system, empty uses the host default.
+		goos: string | *""
+		// goarch specifies the target architecture, empty uses the host default.
+		goarch: string | *""
+	}
+
+	// filter selects which functions are analyzed.
+	// Excluded functions are still described to their callers.
+	filter: {
+		// packages matches package import paths.
+		packages: #Patterns
+		// files matches file base names and full paths, e.g. "*.pb.go".
+		files: #Patterns
+		// functions matches function IDs with and without the package path, e.g. "String" or "/Mock/".
+		functions: #Patterns
+		// generated specifies whether to analyze files marked with "// Code generated ... DO NOT EDIT.".
+		generated: bool | *false
 	}
 
+	// models defines named LLM profiles that passes can refer to by name.
+	models: {[string]: #LLMConfig}
+
 	// pass allows definining set of passes that will be all loaded.
 	pass: {[Name=string]: {{#AnalysisPass} & {name: Name}}}
 	// analyse specifies which passes to run.
 	analyse: [...#AnalysisPass] | *[for k, v in pass { {v} }]
+
+	// fix configures generating code fixes for issues. The LLM is asked to
+	// rewrite the function containing an issue, and the result is kept only
+	// when it parses and type-checks.
+	fix: {
+		// enabled specifies whether fixes are generated.
+		enabled: bool | *false
+		// prompt specifies the prompt file to use.
+		prompt: string | *"builtin:fix"
+		// min_severity specifies the least severe issues to fix, empty fixes all issues.
+		min_severity: string | *""
+		// llm configures the Language Model, in the same forms as #AnalysisPass.llm.
+		llm?: #LLMConfig | string | [string, ...string]
+	}
+
+	// overrides change the passes or the Language Model for some packages or files.
+	// When several overrides match, later ones take precedence.
+	overrides: [...#Override] | *[]
+
+	// severities lists issue severities from the most to the least severe.
+	// Empty uses critical, high, medium, low and info, where critical and
+	// high are SARIF errors, medium is a warning and low and info are notes.
+	severities: [...#Severity] | *[]
+
+	// knowledge adds invariants and pitfalls for external functions keyed by
+	// function ID, e.g. "io.ReadAll" or "sync.(*WaitGroup).Add".
+	// Entries are merged with the builtin standard library knowledge.
+	knowledge: {[string]: #Knowledge}
 }
 
 // Enforce that the package conforms to #Config
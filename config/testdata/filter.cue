@@ -0,0 +1,6 @@
+package config
+
+filter: {
+	packages: exclude: ["example.com/app/gen/..."]
+	files: exclude: ["*_string.go", "*.pb.go"]
+}
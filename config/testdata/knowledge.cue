@@ -0,0 +1,10 @@
+package config
+
+knowledge: {
+	"example.com/db.(*Conn).Query": {
+		pitfalls: ["rows must be closed"]
+	}
+	"io.ReadAll": {
+		invariants: ["reads until EOF"]
+	}
+}
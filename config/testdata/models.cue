@@ -0,0 +1,24 @@
+package config
+
+llm: {
+	provider: "openai"
+	base_url: "http://localhost:8080/v1"
+	model:    "default"
+}
+
+models: {
+	small: {
+		provider: "openai"
+		base_url: "http://localhost:8080/v1"
+		model:    "small"
+	}
+	remote: {
+		provider: "openai"
+		base_url: "https://api.example.com/v1"
+		model:    "large"
+	}
+}
+
+pass: summary: {prompt: "builtin:summary", llm: "small"}
+pass: security: {prompt: "builtin:security", llm: ["small", "remote"]}
+pass: correctness: {prompt: "builtin:correctness"}
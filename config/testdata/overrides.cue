@@ -0,0 +1,24 @@
+package config
+
+llm: {
+	provider: "openai"
+	base_url: "http://localhost:8080/v1"
+	model:    "default"
+}
+
+models: strong: {
+	provider: "openai"
+	base_url: "http://localhost:8080/v1"
+	model:    "strong"
+}
+
+pass: summary: {prompt: "builtin:summary"}
+pass: correctness: {prompt: "builtin:correctness"}
+
+overrides: [{
+	match: ["internal/auth/...", "api/..."]
+	llm:   "strong"
+}, {
+	match: "example.com/app/gen/..."
+	analyse: [pass.summary]
+}]
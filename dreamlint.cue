@@ -21,6 +21,7 @@ output: {
 	json:     "dreamlint-report.json"
 	markdown: "dreamlint-report.md"
 	sarif:    "dreamlint-report.sarif"
+	junit:    "dreamlint-report.junit.xml"
 }
 
 pass: summary: {
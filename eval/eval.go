@@ -0,0 +1,256 @@
+// Package eval measures how well the analysis passes find known issues in a
+// corpus of annotated Go code.
+//
+// A corpus case is a txtar archive of Go files. Expected issues are marked
+// with a comment on the line of the issue:
+//
+//	db.Query("SELECT * FROM users WHERE name = '" + name + "'") // want: security "sql injection"
+//
+// The message is optional and only describes the issue, findings are
+// matched by file, line and category. The comment of the archive may limit
+// the passes scored for the case with a "pass:" line listing pass names.
+package eval
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/txtar"
+)
+
+// Case is a Go module with expected issues
+type Case struct {
+	Name string
+	// Passes limits the scored passes, all passes are scored when empty.
+	Passes   []string
+	Files    []txtar.File
+	Expected []Expectation
+}
+
+// Expectation is an issue that should be found
+type Expectation struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Category string `json:"category"`
+	Message  string `json:"message,omitempty"`
+}
+
+// Finding is a reported issue
+type Finding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	EndLine  int    `json:"end_line,omitempty"`
+	Category string `json:"category"`
+	Message  string `json:"message"`
+}
+
+// LoadCorpus loads the cases of paths, which are txtar files or
+// directories of txtar files.
+func LoadCorpus(paths ...string) ([]*Case, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*.txtar"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+
+	var cases []*Case
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		c, err := ParseCase(file, data)
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+// ParseCase parses a txtar archive with the expected issues in its Go files.
+func ParseCase(name string, data []byte) (*Case, error) {
+	archive := txtar.Parse(data)
+	c := &Case{Name: name, Files: archive.Files}
+
+	for _, line := range strings.Split(string(archive.Comment), "\n") {
+		if passes, ok := strings.CutPrefix(strings.TrimSpace(line), "pass:"); ok {
+			c.Passes = append(c.Passes, strings.Fields(passes)...)
+		}
+	}
+
+	for _, file := range archive.Files {
+		if !strings.HasSuffix(file.Name, ".go") {
+			continue
+		}
+		expected, err := ParseExpectations(file.Name, file.Data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		c.Expected = append(c.Expected, expected...)
+	}
+	if len(c.Expected) == 0 && len(c.Passes) == 0 {
+		// A case without expectations is scored for false positives only,
+		// which is easy to do by accident, so it has to be explicit.
+		return nil, fmt.Errorf("%s: no expected issues and no pass: line", name)
+	}
+	return c, nil
+}
+
+// wantRx matches a `// want: category "message"` comment.
+var wantRx = regexp.MustCompile(`//\s*want:\s*([\w-]+)(?:\s+("(?:[^"\\]|\\.)*"))?`)
+
+// ParseExpectations returns the expected issues marked in the Go source of file.
+func ParseExpectations(file string, src []byte) ([]Expectation, error) {
+	var expected []Expectation
+	for i, line := range strings.Split(string(src), "\n") {
+		m := wantRx.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		expectation := Expectation{File: file, Line: i + 1, Category: m[1]}
+		if m[2] != "" {
+			message, err := strconv.Unquote(m[2])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid message %s: %w", file, i+1, m[2], err)
+			}
+			expectation.Message = message
+		}
+		expected = append(expected, expectation)
+	}
+	return expected, nil
+}
+
+// Extract writes the files of the case to dir, adding a go.mod when the
+// case does not have one.
+func (c *Case) Extract(dir string) error {
+	hasMod := false
+	for _, file := range c.Files {
+		path := filepath.Join(dir, filepath.FromSlash(file.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, file.Data, 0644); err != nil {
+			return err
+		}
+		hasMod = hasMod || file.Name == "go.mod"
+	}
+	if hasMod {
+		return nil
+	}
+	return os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module eval\n\ngo 1.22\n"), 0644)
+}
+
+// Scores reports whether pass is scored for the case.
+func (c *Case) Scores(pass string) bool {
+	return len(c.Passes) == 0 || slices.Contains(c.Passes, pass)
+}
+
+// Result is the outcome of a pass on a case
+type Result struct {
+	Case  string `json:"case"`
+	Pass  string `json:"pass"`
+	Model string `json:"model"`
+	// Found are the expected issues that were found.
+	Found      []Expectation `json:"found,omitempty"`
+	Missed     []Expectation `json:"missed,omitempty"`
+	Unexpected []Finding     `json:"unexpected,omitempty"`
+}
+
+// Match matches the findings of a pass to the expected issues of the pass.
+// A finding matches an expectation in the same file and category when the
+// expected line is within the lines of the finding. Each finding matches at
+// most one expectation.
+func Match(expected []Expectation, findings []Finding) (found, missed []Expectation, unexpected []Finding) {
+	matched := make([]bool, len(findings))
+	for _, expectation := range expected {
+		i := -1
+		for k, finding := range findings {
+			if !matched[k] && finding.File == expectation.File && finding.Category == expectation.Category &&
+				finding.Line <= expectation.Line && expectation.Line <= max(finding.Line, finding.EndLine) {
+				i = k
+				break
+			}
+		}
+		if i < 0 {
+			missed = append(missed, expectation)
+			continue
+		}
+		matched[i] = true
+		found = append(found, expectation)
+	}
+	for i, finding := range findings {
+		if !matched[i] {
+			unexpected = append(unexpected, finding)
+		}
+	}
+	return found, missed, unexpected
+}
+
+// Score is the precision and recall of a pass and model
+type Score struct {
+	Pass           string  `json:"pass"`
+	Model          string  `json:"model"`
+	Cases          int     `json:"cases"`
+	TruePositives  int     `json:"true_positives"`
+	FalsePositives int     `json:"false_positives"`
+	FalseNegatives int     `json:"false_negatives"`
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+	F1             float64 `json:"f1"`
+}
+
+// Scores sums the results by pass and model, sorted by pass and model.
+// Precision is 0 when nothing was found and recall is 0 when nothing was
+// expected.
+func Scores(results []Result) []Score {
+	var scores []Score
+	for _, result := range results {
+		i := slices.IndexFunc(scores, func(score Score) bool {
+			return score.Pass == result.Pass && score.Model == result.Model
+		})
+		if i < 0 {
+			scores = append(scores, Score{Pass: result.Pass, Model: result.Model})
+			i = len(scores) - 1
+		}
+		score := &scores[i]
+		score.Cases++
+		score.TruePositives += len(result.Found)
+		score.FalsePositives += len(result.Unexpected)
+		score.FalseNegatives += len(result.Missed)
+	}
+
+	for i := range scores {
+		score := &scores[i]
+		if found := score.TruePositives + score.FalsePositives; found > 0 {
+			score.Precision = float64(score.TruePositives) / float64(found)
+		}
+		if expected := score.TruePositives + score.FalseNegatives; expected > 0 {
+			score.Recall = float64(score.TruePositives) / float64(expected)
+		}
+		if score.Precision+score.Recall > 0 {
+			score.F1 = 2 * score.Precision * score.Recall / (score.Precision + score.Recall)
+		}
+	}
+	slices.SortFunc(scores, func(a, b Score) int {
+		return cmp.Or(cmp.Compare(a.Pass, b.Pass), cmp.Compare(a.Model, b.Model))
+	})
+	return scores
+}
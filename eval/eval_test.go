@@ -0,0 +1,114 @@
+package eval
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseCase(t *testing.T) {
+	data := []byte(`Lookup with a query built from input.
+
+pass: security correctness
+
+-- db/users.go --
+package db
+
+func Find(name string) {
+	query("SELECT * FROM users WHERE name = '" + name + "'") // want: security "sql \"injection\""
+	go leak() // want: concurrency
+}
+-- README --
+// want: security "not Go"
+`)
+	c, err := ParseCase("users.txtar", data)
+	if err != nil {
+		t.Fatalf("ParseCase: %v", err)
+	}
+	if want := []string{"security", "correctness"}; !reflect.DeepEqual(c.Passes, want) {
+		t.Errorf("passes = %q, want %q", c.Passes, want)
+	}
+	want := []Expectation{
+		{File: "db/users.go", Line: 4, Category: "security", Message: `sql "injection"`},
+		{File: "db/users.go", Line: 5, Category: "concurrency"},
+	}
+	if !reflect.DeepEqual(c.Expected, want) {
+		t.Errorf("expected = %+v, want %+v", c.Expected, want)
+	}
+	if !c.Scores("correctness") || c.Scores("concurrency") {
+		t.Errorf("scored passes do not follow the pass: line")
+	}
+
+	dir := t.TempDir()
+	if err := c.Extract(dir); err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	for _, name := range []string{"go.mod", "db/users.go", "README"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("missing extracted file: %v", err)
+		}
+	}
+
+	if _, err := ParseCase("empty.txtar", []byte("-- a.go --\npackage a\n")); err == nil {
+		t.Error("expected an error for a case without expectations or passes")
+	}
+}
+
+func TestLoadCorpus(t *testing.T) {
+	cases, err := LoadCorpus("../testdata/eval")
+	if err != nil {
+		t.Fatalf("LoadCorpus: %v", err)
+	}
+	if len(cases) == 0 {
+		t.Fatal("no cases loaded")
+	}
+	for _, c := range cases {
+		if len(c.Expected) == 0 {
+			t.Errorf("%s: no expected issues", c.Name)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	expected := []Expectation{
+		{File: "a.go", Line: 10, Category: "security"},
+		{File: "a.go", Line: 12, Category: "security"},
+		{File: "a.go", Line: 20, Category: "security"},
+	}
+	findings := []Finding{
+		// Covers line 10 and 12, but matches only one of them
+		{File: "a.go", Line: 9, EndLine: 12, Category: "security", Message: "wide"},
+		{File: "a.go", Line: 12, Category: "security", Message: "exact"},
+		{File: "a.go", Line: 20, Category: "correctness", Message: "other category"},
+		{File: "b.go", Line: 20, Category: "security", Message: "other file"},
+	}
+
+	found, missed, unexpected := Match(expected, findings)
+	if len(found) != 2 || found[0].Line != 10 || found[1].Line != 12 {
+		t.Errorf("found = %+v", found)
+	}
+	if len(missed) != 1 || missed[0].Line != 20 {
+		t.Errorf("missed = %+v", missed)
+	}
+	if len(unexpected) != 2 || unexpected[0].Message != "other category" || unexpected[1].Message != "other file" {
+		t.Errorf("unexpected = %+v", unexpected)
+	}
+}
+
+func TestScores(t *testing.T) {
+	results := []Result{
+		{Case: "a", Pass: "security", Model: "m", Found: make([]Expectation, 3), Unexpected: make([]Finding, 1)},
+		{Case: "b", Pass: "security", Model: "m", Missed: make([]Expectation, 3)},
+		{Case: "a", Pass: "correctness", Model: "m", Unexpected: make([]Finding, 2)},
+	}
+	scores := Scores(results)
+	want := []Score{
+		{Pass: "correctness", Model: "m", Cases: 1, FalsePositives: 2},
+		{Pass: "security", Model: "m", Cases: 2, TruePositives: 3, FalsePositives: 1, FalseNegatives: 3,
+			Precision: 0.75, Recall: 0.5, F1: 0.6},
+	}
+	if !reflect.DeepEqual(scores, want) {
+		t.Errorf("scores = %+v\nwant %+v", scores, want)
+	}
+}
@@ -1,7 +1,6 @@
 package extract
 
 import (
-	"fmt"
 	"slices"
 
 	"golang.org/x/tools/go/callgraph/cha"
@@ -11,7 +10,7 @@ import (
 )
 
 // BuildCallgraph builds a callgraph using CHA analysis from loaded packages.
-// Returns a map from function ID to list of callee IDs.
+// Returns a map from function ID to list of callee IDs, see FuncID for the ID format.
 func BuildCallgraph(p *Packages) map[string][]string {
 	return BuildCallgraphFromPackages(p.Pkgs)
 }
@@ -33,10 +32,11 @@ func BuildCallgraphFromPackages(pkgs []*packages.Package) map[string][]string {
 			continue
 		}
 
-		callerID := funcID(fn)
-		if callerID == "" {
+		caller, ok := ssaFuncID(fn)
+		if !ok {
 			continue
 		}
+		callerID := caller.String()
 
 		// Initialize entry even if no callees
 		if _, ok := graph[callerID]; !ok {
@@ -48,10 +48,11 @@ func BuildCallgraphFromPackages(pkgs []*packages.Package) map[string][]string {
 				continue
 			}
 
-			calleeID := funcID(edge.Callee.Func)
-			if calleeID == "" {
+			callee, ok := ssaFuncID(edge.Callee.Func)
+			if !ok {
 				continue
 			}
+			calleeID := callee.String()
 
 			// Avoid duplicates
 			if !slices.Contains(graph[callerID], calleeID) {
@@ -62,19 +63,3 @@ func BuildCallgraphFromPackages(pkgs []*packages.Package) map[string][]string {
 
 	return graph
 }
-
-func funcID(fn *ssa.Function) string {
-	if fn.Pkg == nil {
-		return ""
-	}
-
-	pkg := fn.Pkg.Pkg.Path()
-	name := fn.Name()
-
-	// Handle methods
-	if recv := fn.Signature.Recv(); recv != nil {
-		return fmt.Sprintf("%s.(%s).%s", pkg, recv.Type().String(), name)
-	}
-
-	return fmt.Sprintf("%s.%s", pkg, name)
-}
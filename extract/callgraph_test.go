@@ -50,3 +50,83 @@ func C() {}
 		t.Errorf("C should call nothing, got %v", graph["testpkg.C"])
 	}
 }
+
+func TestBuildCallgraph_Generics(t *testing.T) {
+	dir := t.TempDir()
+
+	goMod := `module testpkg
+
+go 1.25
+`
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	goFile := `package testpkg
+
+func Map[T, R any](xs []T, fn func(T) R) []R {
+	var rs []R
+	for _, x := range xs {
+		rs = append(rs, fn(x))
+	}
+	return rs
+}
+
+type List[E any] struct{ items []E }
+
+func (l *List[E]) Push(e E) { l.items = append(l.items, e); l.grow() }
+
+// grow uses a different type parameter name than the type declaration.
+func (l *List[T]) grow() {}
+
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+func (p Pair[K, V]) Swap() Pair[K, V] { return p }
+
+func Use() {
+	var l List[int]
+	l.Push(1)
+	Map([]int{1}, func(x int) string { return "" })
+	Pair[string, int]{}.Swap()
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(goFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pkgs, err := LoadPackages(dir, "./...")
+	if err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	graph := BuildCallgraph(pkgs)
+
+	for _, callee := range []string{"testpkg.(*List).Push", "testpkg.Map", "testpkg.(Pair).Swap"} {
+		if !slices.Contains(graph["testpkg.Use"], callee) {
+			t.Errorf("Use should call %s, got %v", callee, graph["testpkg.Use"])
+		}
+	}
+	if !slices.Contains(graph["testpkg.(*List).Push"], "testpkg.(*List).grow") {
+		t.Errorf("Push should call grow, got %v", graph["testpkg.(*List).Push"])
+	}
+
+	// IDs from the callgraph must match the extracted functions
+	units := BuildAnalysisUnits(ExtractFunctions(pkgs), graph)
+	var use *AnalysisUnit
+	for _, unit := range units {
+		if unit.ID == "testpkg.Use" {
+			use = unit
+		}
+	}
+	if use == nil {
+		t.Fatal("unit testpkg.Use not found")
+	}
+	for _, callee := range []string{"testpkg.(*List).Push", "testpkg.Map", "testpkg.(Pair).Swap"} {
+		if !slices.Contains(use.Callees, callee) {
+			t.Errorf("Use unit should have callee %s, got %v", callee, use.Callees)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"path/filepath"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// CheckFile parses and type-checks src as a replacement of the file filename
+// in its loaded package. Imports are resolved only from the imports of the
+// package, so src cannot add new dependencies.
+func (p *Packages) CheckFile(filename string, src []byte) error {
+	pkg, index := p.packageOf(filename)
+	if pkg == nil {
+		return fmt.Errorf("%s is not part of the loaded packages", filename)
+	}
+
+	file, err := parser.ParseFile(pkg.Fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return err
+	}
+	files := make([]*ast.File, len(pkg.Syntax))
+	copy(files, pkg.Syntax)
+	files[index] = file
+
+	var errs []error
+	conf := types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if path == "unsafe" {
+				return types.Unsafe, nil
+			}
+			if imp, ok := pkg.Imports[path]; ok && imp.Types != nil {
+				return imp.Types, nil
+			}
+			return nil, fmt.Errorf("package %s is not imported by %s", path, pkg.PkgPath)
+		}),
+		Sizes: pkg.TypesSizes,
+		Error: func(err error) {
+			// The first errors are the most useful, the rest tend to follow from them
+			if len(errs) < 5 {
+				errs = append(errs, err)
+			}
+		},
+	}
+	if pkg.Module != nil && pkg.Module.GoVersion != "" {
+		conf.GoVersion = "go" + pkg.Module.GoVersion
+	}
+	_, _ = conf.Check(pkg.PkgPath, pkg.Fset, files, nil)
+	return errors.Join(errs...)
+}
+
+// packageOf returns the package containing filename and the index of its syntax.
+func (p *Packages) packageOf(filename string) (*packages.Package, int) {
+	filename = filepath.Clean(filename)
+	for _, pkg := range p.Pkgs {
+		for i, file := range pkg.Syntax {
+			if filepath.Clean(pkg.Fset.Position(file.Pos()).Filename) == filename {
+				return pkg, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+// PackagePath returns the import path of the package containing filename,
+// or "" when the file is not part of the loaded packages.
+func (p *Packages) PackagePath(filename string) string {
+	pkg, _ := p.packageOf(filename)
+	if pkg == nil {
+		return ""
+	}
+	return pkg.PkgPath
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
@@ -11,27 +11,46 @@ import (
 
 // ExternalFunc holds shallow info about external dependencies.
 type ExternalFunc struct {
-	Package   string
-	Name      string
-	Signature string
-	Godoc     string
+	Package    string
+	Name       string
+	Receiver   string
+	Signature  string
+	Godoc      string
+	Invariants []string
+	Pitfalls   []string
 }
 
 // ExtractExternalFuncs finds functions called from the analyzed packages that are
 // defined in external packages (dependencies). Returns a map from function ID to ExternalFunc.
-func ExtractExternalFuncs(p *Packages, graph map[string][]string) map[string]*ExternalFunc {
+// Invariants and pitfalls are filled in from knowledge, which may be nil.
+func ExtractExternalFuncs(p *Packages, graph map[string][]string, knowledge Knowledge) map[string]*ExternalFunc {
 	// Build set of internal package paths
 	internal := make(map[string]bool)
 	for _, pkg := range p.Pkgs {
 		internal[pkg.PkgPath] = true
 	}
 
+	// Functions excluded by the filter are treated as external
+	excluded := make(map[string]*FunctionInfo)
+	if p.Filter != nil {
+		for _, fn := range ExtractFunctionsFromPackages(p.Pkgs) {
+			if !p.Filter.Match(fn) {
+				excluded[fn.ID()] = fn
+			}
+		}
+	}
+
 	// Collect all callee IDs that are external
 	externalIDs := make(map[string]bool)
 	for _, callees := range graph {
 		for _, calleeID := range callees {
-			pkg := packageFromID(calleeID)
-			if pkg != "" && !internal[pkg] {
+			id, err := ParseFuncID(calleeID)
+			if err != nil || id.Closure != "" {
+				continue
+			}
+			if !internal[id.Package] {
+				externalIDs[calleeID] = true
+			} else if _, ok := excluded[calleeID]; ok {
 				externalIDs[calleeID] = true
 			}
 		}
@@ -47,28 +66,31 @@ func ExtractExternalFuncs(p *Packages, graph map[string][]string) map[string]*Ex
 	// Extract info for each external function
 	result := make(map[string]*ExternalFunc)
 	for id := range externalIDs {
-		if ext := extractExternalFunc(id, allPkgs); ext != nil {
-			result[id] = ext
+		var ext *ExternalFunc
+		if fn, ok := excluded[id]; ok {
+			ext = &ExternalFunc{
+				Package:   fn.Package,
+				Name:      fn.Name,
+				Receiver:  fn.Receiver,
+				Signature: fn.Signature,
+				Godoc:     fn.Godoc,
+			}
+		} else {
+			ext = extractExternalFunc(id, allPkgs)
+		}
+		if ext == nil {
+			continue
+		}
+		if entry, ok := knowledge[id]; ok {
+			ext.Invariants = entry.Invariants
+			ext.Pitfalls = entry.Pitfalls
 		}
+		result[id] = ext
 	}
 
 	return result
 }
 
-// packageFromID extracts the package path from a function ID.
-// e.g., "fmt.Println" -> "fmt", "github.com/foo/bar.Baz" -> "github.com/foo/bar"
-func packageFromID(id string) string {
-	// Handle method IDs like "pkg.(*Type).Method"
-	if idx := strings.Index(id, ".("); idx != -1 {
-		return id[:idx]
-	}
-	// Handle function IDs like "pkg.Func"
-	if idx := strings.LastIndex(id, "."); idx != -1 {
-		return id[:idx]
-	}
-	return ""
-}
-
 // collectAllPackages traverses imports to collect all packages.
 func collectAllPackages(pkgs []*packages.Package) map[string]*packages.Package {
 	result := make(map[string]*packages.Package)
@@ -90,30 +112,29 @@ func collectAllPackages(pkgs []*packages.Package) map[string]*packages.Package {
 
 // extractExternalFunc extracts function info from loaded packages.
 func extractExternalFunc(id string, allPkgs map[string]*packages.Package) *ExternalFunc {
-	pkgPath := packageFromID(id)
-	pkg, ok := allPkgs[pkgPath]
-	if !ok || pkg.Types == nil {
+	fid, err := ParseFuncID(id)
+	if err != nil {
 		return nil
 	}
-
-	// Parse the function/method name from the ID
-	name, recv := parseFuncID(id, pkgPath)
-	if name == "" {
+	pkg, ok := allPkgs[fid.Package]
+	if !ok || pkg.Types == nil {
 		return nil
 	}
 
 	ext := &ExternalFunc{
-		Package: pkgPath,
-		Name:    name,
+		Package: fid.Package,
+		Name:    fid.Name,
 	}
 
 	// Look up the function in the package's type info
-	if recv != "" {
-		// Method lookup
-		ext.Signature, ext.Godoc = lookupMethod(pkg, recv, name)
+	if fid.IsMethod() {
+		ext.Receiver = fid.Receiver
+		if fid.Pointer {
+			ext.Receiver = "*" + fid.Receiver
+		}
+		ext.Signature, ext.Godoc = lookupMethod(pkg, ext.Receiver, fid.Name)
 	} else {
-		// Function lookup
-		ext.Signature, ext.Godoc = lookupFunc(pkg, name)
+		ext.Signature, ext.Godoc = lookupFunc(pkg, fid.Name)
 	}
 
 	if ext.Signature == "" {
@@ -123,24 +144,6 @@ func extractExternalFunc(id string, allPkgs map[string]*packages.Package) *Exter
 	return ext
 }
 
-// parseFuncID parses a function ID into name and optional receiver type.
-// Returns (name, receiver) where receiver is empty for plain functions.
-func parseFuncID(id, pkgPath string) (name, recv string) {
-	suffix := strings.TrimPrefix(id, pkgPath+".")
-
-	// Check for method: (*Type).Method or (Type).Method
-	if strings.HasPrefix(suffix, "(") {
-		if idx := strings.Index(suffix, ")."); idx != -1 {
-			recv = suffix[1:idx]
-			name = suffix[idx+2:]
-			return name, recv
-		}
-	}
-
-	// Plain function
-	return suffix, ""
-}
-
 // lookupFunc finds a function's signature and godoc.
 func lookupFunc(pkg *packages.Package, name string) (sig, godoc string) {
 	obj := pkg.Types.Scope().Lookup(name)
@@ -236,9 +239,11 @@ func recvTypeName(expr ast.Expr) string {
 	case *ast.Ident:
 		return t.Name
 	case *ast.StarExpr:
-		if id, ok := t.X.(*ast.Ident); ok {
-			return id.Name
-		}
+		return recvTypeName(t.X)
+	case *ast.IndexExpr:
+		return recvTypeName(t.X)
+	case *ast.IndexListExpr:
+		return recvTypeName(t.X)
 	}
 	return ""
 }
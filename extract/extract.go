@@ -1,10 +1,13 @@
 package extract
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"go/ast"
 	"go/printer"
 	"go/token"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"golang.org/x/tools/go/packages"
@@ -19,11 +22,72 @@ type FunctionInfo struct {
 	Body      string
 	Godoc     string
 	Position  token.Position
+	Generated bool   // declared in a generated file
+	FileHash  string // FileHash of the file content when the function was extracted
 }
 
-// ExtractFunctions extracts all function information from loaded packages.
+// FileHash returns the hash used to detect changes of a source file.
+func FileHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// FuncID returns the identity of the function.
+func (f *FunctionInfo) FuncID() FuncID {
+	id := FuncID{Package: f.Package, Name: f.Name}
+	if f.Receiver != "" {
+		recv := strings.TrimPrefix(f.Receiver, "*")
+		// Type parameters of generic receivers are omitted
+		if i := strings.Index(recv, "["); i >= 0 {
+			recv = recv[:i]
+		}
+		id.Receiver = recv
+		id.Pointer = strings.HasPrefix(f.Receiver, "*")
+	}
+	return id
+}
+
+// ID returns the function ID, e.g. "pkg.Func" or "pkg.(*T).Method".
+func (f *FunctionInfo) ID() string {
+	return f.FuncID().String()
+}
+
+// EndLine returns the last line of the function declaration.
+func (f *FunctionInfo) EndLine() int {
+	return f.Position.Line + strings.Count(f.Body, "\n")
+}
+
+// FunctionAt returns the function declared at the given file and line.
+// filename may be a path suffix of the file, e.g. "pkg/file.go".
+// It returns nil if no function contains the line.
+func FunctionAt(funcs []*FunctionInfo, filename string, line int) *FunctionInfo {
+	filename = filepath.ToSlash(filepath.Clean(filename))
+	for _, fn := range funcs {
+		path := filepath.ToSlash(fn.Position.Filename)
+		if path != filename && !strings.HasSuffix(path, "/"+filename) {
+			continue
+		}
+		if fn.Position.Line <= line && line <= fn.EndLine() {
+			return fn
+		}
+	}
+	return nil
+}
+
+// ExtractFunctions extracts function information from loaded packages,
+// skipping functions that do not match p.Filter.
 func ExtractFunctions(p *Packages) []*FunctionInfo {
-	return ExtractFunctionsFromPackages(p.Pkgs)
+	funcs := ExtractFunctionsFromPackages(p.Pkgs)
+	if p.Filter == nil {
+		return funcs
+	}
+	filtered := funcs[:0]
+	for _, fn := range funcs {
+		if p.Filter.Match(fn) {
+			filtered = append(filtered, fn)
+		}
+	}
+	return filtered
 }
 
 // ExtractFunctionsFromPackages extracts function information from a slice of packages.
@@ -51,6 +115,11 @@ func ExtractFunctionsFromPackages(pkgs []*packages.Package) []*FunctionInfo {
 		for _, file := range pkg.Syntax {
 			filePos := pkg.Fset.Position(file.Pos())
 			content := fileContents[filePos.Filename]
+			generated := ast.IsGenerated(file)
+			var fileHash string
+			if content != nil {
+				fileHash = FileHash(content)
+			}
 
 			for _, decl := range file.Decls {
 				fn, ok := decl.(*ast.FuncDecl)
@@ -66,9 +135,11 @@ func ExtractFunctionsFromPackages(pkgs []*packages.Package) []*FunctionInfo {
 				endPos := fn.End()
 
 				info := &FunctionInfo{
-					Package:  pkg.PkgPath,
-					Name:     fn.Name.Name,
-					Position: pkg.Fset.Position(startPos),
+					Package:   pkg.PkgPath,
+					Name:      fn.Name.Name,
+					Position:  pkg.Fset.Position(startPos),
+					Generated: generated,
+					FileHash:  fileHash,
 				}
 
 				// Extract receiver
@@ -1,6 +1,7 @@
 package extract
 
 import (
+	"go/token"
 	"os"
 	"path/filepath"
 	"testing"
@@ -63,3 +64,35 @@ func helper() {}
 		t.Error("Hello godoc is empty")
 	}
 }
+
+func TestFunctionAt(t *testing.T) {
+	funcs := []*FunctionInfo{
+		{Name: "A", Position: token.Position{Filename: "/src/pkg/a.go", Line: 3}, Body: "func A() {\n}"},
+		{Name: "B", Position: token.Position{Filename: "/src/pkg/a.go", Line: 6}, Body: "func B() {\n\tA()\n}"},
+		{Name: "C", Position: token.Position{Filename: "/src/other/a.go", Line: 3}, Body: "func C() {}"},
+	}
+
+	tests := []struct {
+		file string
+		line int
+		want string
+	}{
+		{"a.go", 3, "A"},
+		{"a.go", 4, "A"},
+		{"a.go", 5, ""},
+		{"pkg/a.go", 7, "B"},
+		{"/src/pkg/a.go", 8, "B"},
+		{"other/a.go", 3, "C"},
+		{"g/a.go", 3, ""},
+		{"b.go", 3, ""},
+	}
+	for _, test := range tests {
+		got := ""
+		if fn := FunctionAt(funcs, test.file, test.line); fn != nil {
+			got = fn.Name
+		}
+		if got != test.want {
+			t.Errorf("FunctionAt(%q, %d) = %q, want %q", test.file, test.line, got, test.want)
+		}
+	}
+}
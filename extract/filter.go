@@ -0,0 +1,147 @@
+package extract
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Filter selects which functions are analyzed.
+type Filter struct {
+	Packages  Patterns // matched against the package import path
+	Files     Patterns // matched against the file base name and full path
+	Functions Patterns // matched against the function ID and the ID without package path
+	Generated bool     // include functions from generated files
+}
+
+// Match reports whether fn passes the filter.
+func (f *Filter) Match(fn *FunctionInfo) bool {
+	if f == nil {
+		return true
+	}
+	if fn.Generated && !f.Generated {
+		return false
+	}
+	if !f.Packages.Match(fn.Package) {
+		return false
+	}
+	if !f.Files.Match(filepath.Base(fn.Position.Filename), fn.Position.Filename) {
+		return false
+	}
+	id := fn.ID()
+	return f.Functions.Match(id, strings.TrimPrefix(id, fn.Package+"."))
+}
+
+// Patterns holds compiled include and exclude patterns.
+type Patterns struct {
+	Include []*Pattern
+	Exclude []*Pattern
+}
+
+// CompilePatterns compiles include and exclude pattern lists.
+func CompilePatterns(include, exclude []string) (Patterns, error) {
+	var ps Patterns
+	for _, s := range include {
+		p, err := CompilePattern(s)
+		if err != nil {
+			return Patterns{}, err
+		}
+		ps.Include = append(ps.Include, p)
+	}
+	for _, s := range exclude {
+		p, err := CompilePattern(s)
+		if err != nil {
+			return Patterns{}, err
+		}
+		ps.Exclude = append(ps.Exclude, p)
+	}
+	return ps, nil
+}
+
+// Match reports whether any of the values is included and none is excluded.
+// An empty include list includes everything.
+func (ps Patterns) Match(values ...string) bool {
+	if len(ps.Include) > 0 && !anyMatch(ps.Include, values) {
+		return false
+	}
+	return !anyMatch(ps.Exclude, values)
+}
+
+func anyMatch(patterns []*Pattern, values []string) bool {
+	for _, p := range patterns {
+		for _, v := range values {
+			if p.Match(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Pattern is a compiled glob or regular expression.
+type Pattern struct {
+	text string
+	re   *regexp.Regexp
+}
+
+// CompilePattern compiles a pattern.
+//
+// A pattern enclosed in slashes, e.g. "/_string\.go$/", is an unanchored
+// regular expression. Any other pattern is a glob matching the whole value,
+// where "*" matches any sequence except "/", "?" matches a single character
+// except "/" and "..." matches any sequence including "/". A trailing "/..."
+// also matches the prefix itself, so "example.com/app/..." matches
+// "example.com/app". A backslash escapes the next character.
+func CompilePattern(s string) (*Pattern, error) {
+	if len(s) >= 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+		re, err := regexp.Compile(s[1 : len(s)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", s, err)
+		}
+		return &Pattern{text: s, re: re}, nil
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	glob := s
+	suffix := ""
+	if strings.HasSuffix(glob, "/...") {
+		glob = strings.TrimSuffix(glob, "/...")
+		suffix = "(/.*)?"
+	}
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "..."):
+			expr.WriteString(".*")
+			i += 2
+		case glob[i] == '*':
+			expr.WriteString("[^/]*")
+		case glob[i] == '?':
+			expr.WriteString("[^/]")
+		case glob[i] == '\\' && i+1 < len(glob):
+			i++
+			expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	expr.WriteString(suffix)
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", s, err)
+	}
+	return &Pattern{text: s, re: re}, nil
+}
+
+// Match reports whether s matches the pattern.
+func (p *Pattern) Match(s string) bool {
+	return p.re.MatchString(s)
+}
+
+// String returns the pattern as written.
+func (p *Pattern) String() string {
+	return p.text
+}
@@ -0,0 +1,184 @@
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{"*_string.go", "color_string.go", true},
+		{"*_string.go", "dir/color_string.go", false},
+		{"*.pb.go", "api.pb.go", true},
+		{"*.pb.go", "api_pb.go", false},
+		{"example.com/app/...", "example.com/app", true},
+		{"example.com/app/...", "example.com/app/internal/auth", true},
+		{"example.com/app/...", "example.com/application", false},
+		{".../mocks/...", "/src/app/mocks/db.go", true},
+		{"String", "String", true},
+		{"(\\*T).*", "(*T).Close", true},
+		{"(\\*T).*", "(T).Close", false},
+		{"/Mock[A-Z]/", "(*MockDB).Query", true},
+		{"/^Mock/", "(*MockDB).Query", false},
+	}
+
+	for _, tt := range tests {
+		p, err := CompilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("CompilePattern(%q): %v", tt.pattern, err)
+		}
+		if got := p.Match(tt.value); got != tt.want {
+			t.Errorf("%q.Match(%q) = %v, want %v", tt.pattern, tt.value, got, tt.want)
+		}
+	}
+
+	if _, err := CompilePattern("/[/"); err == nil {
+		t.Error("expected error for invalid regular expression")
+	}
+}
+
+func TestPatterns_Match(t *testing.T) {
+	ps, err := CompilePatterns([]string{"example.com/app/..."}, []string{"example.com/app/gen/..."})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ps.Match("example.com/app/server") {
+		t.Error("server should be included")
+	}
+	if ps.Match("example.com/app/gen/proto") {
+		t.Error("gen/proto should be excluded")
+	}
+	if ps.Match("example.com/other") {
+		t.Error("other should not be included")
+	}
+
+	var empty Patterns
+	if !empty.Match("anything") {
+		t.Error("empty patterns should match everything")
+	}
+}
+
+func TestFilter_Functions(t *testing.T) {
+	// The patterns of the README example
+	functions, err := CompilePatterns(nil, []string{"*.String", "/Mock/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter := &Filter{Functions: functions}
+
+	tests := []struct {
+		fn   FunctionInfo
+		want bool
+	}{
+		{FunctionInfo{Package: "example.com/app", Name: "String", Receiver: "ID"}, false},
+		{FunctionInfo{Package: "example.com/app", Name: "String", Receiver: "*User"}, false},
+		{FunctionInfo{Package: "example.com/app", Name: "Query", Receiver: "*MockDB"}, false},
+		{FunctionInfo{Package: "example.com/app", Name: "NewMockDB"}, false},
+		{FunctionInfo{Package: "example.com/app", Name: "Query", Receiver: "*DB"}, true},
+	}
+	for _, test := range tests {
+		if got := filter.Match(&test.fn); got != test.want {
+			t.Errorf("Match(%s) = %v, want %v", test.fn.ID(), got, test.want)
+		}
+	}
+}
+
+func TestExtractFunctions_Filter(t *testing.T) {
+	dir := t.TempDir()
+
+	goMod := `module testpkg
+
+go 1.25
+`
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	goFile := `package testpkg
+
+func A() { B(); C() }
+
+func skipped() {}
+`
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(goFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	genFile := `// Code generated by stringer. DO NOT EDIT.
+
+package testpkg
+
+// B is generated.
+func B() {}
+`
+	if err := os.WriteFile(filepath.Join(dir, "b_string.go"), []byte(genFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mockFile := `package testpkg
+
+// C is a mock.
+func C() {}
+`
+	if err := os.WriteFile(filepath.Join(dir, "c_mock.go"), []byte(mockFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pkgs, err := LoadPackages(dir, "./...")
+	if err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	files, err := CompilePatterns(nil, []string{"*_mock.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	functions, err := CompilePatterns(nil, []string{"skip*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgs.Filter = &Filter{Files: files, Functions: functions}
+
+	funcs := ExtractFunctions(pkgs)
+	if len(funcs) != 1 || funcs[0].Name != "A" {
+		var names []string
+		for _, f := range funcs {
+			names = append(names, f.Name)
+		}
+		t.Fatalf("got functions %v, want [A]", names)
+	}
+
+	// Excluded functions are described as external callees
+	graph := BuildCallgraph(pkgs)
+	externalFuncs := ExtractExternalFuncs(pkgs, graph, nil)
+	for _, id := range []string{"testpkg.B", "testpkg.C"} {
+		ext, ok := externalFuncs[id]
+		if !ok {
+			t.Errorf("%s should be an external function", id)
+			continue
+		}
+		if ext.Godoc == "" {
+			t.Errorf("%s godoc is empty", id)
+		}
+	}
+
+	units := BuildAnalysisUnits(funcs, graph)
+	if len(units) != 1 {
+		t.Fatalf("got %d units, want 1", len(units))
+	}
+	if len(units[0].External) != 2 {
+		t.Errorf("A external should be [testpkg.B testpkg.C], got %v", units[0].External)
+	}
+
+	// Generated files can be included explicitly
+	pkgs.Filter.Generated = true
+	if got := len(ExtractFunctions(pkgs)); got != 2 {
+		t.Errorf("got %d functions with generated files, want 2", got)
+	}
+}
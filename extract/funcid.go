@@ -0,0 +1,162 @@
+package extract
+
+import (
+	"fmt"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// FuncID identifies a function independent of how it was found.
+//
+// The string form, used as key in callgraphs and reports, is
+//
+//	pkg/path.Func
+//	pkg/path.(Type).Method
+//	pkg/path.(*Type).Method
+//	pkg/path.Func$1$2
+//
+// where Type has no package qualifier or type parameters and the "$N"
+// suffixes identify the N-th function literal inside the enclosing function.
+type FuncID struct {
+	Package  string // package import path
+	Receiver string // receiver type name, empty for plain functions
+	Pointer  bool   // whether the receiver is a pointer
+	Name     string // function or method name
+	Closure  string // "$"-separated 1-based function literal indices, e.g. "1$2"
+}
+
+// String formats the ID, see FuncID for the format.
+func (id FuncID) String() string {
+	var b strings.Builder
+	b.WriteString(id.Package)
+	b.WriteString(".")
+	if id.Receiver != "" {
+		b.WriteString("(")
+		if id.Pointer {
+			b.WriteString("*")
+		}
+		b.WriteString(id.Receiver)
+		b.WriteString(").")
+	}
+	b.WriteString(id.Name)
+	if id.Closure != "" {
+		b.WriteString("$")
+		b.WriteString(id.Closure)
+	}
+	return b.String()
+}
+
+// IsMethod reports whether id refers to a method.
+func (id FuncID) IsMethod() bool {
+	return id.Receiver != ""
+}
+
+// Enclosing returns the ID of the declared function containing a closure.
+// For declared functions it returns id unchanged.
+func (id FuncID) Enclosing() FuncID {
+	id.Closure = ""
+	return id
+}
+
+// ParseFuncID parses the string form of a FuncID.
+func ParseFuncID(s string) (FuncID, error) {
+	var id FuncID
+
+	rest := s
+	if idx := strings.Index(s, ".("); idx != -1 {
+		// Method: pkg.(*Type).Method
+		id.Package = s[:idx]
+		end := strings.Index(s[idx:], ").")
+		if end == -1 {
+			return FuncID{}, fmt.Errorf("invalid function ID %q: unterminated receiver", s)
+		}
+		recv := s[idx+2 : idx+end]
+		id.Pointer = strings.HasPrefix(recv, "*")
+		id.Receiver = strings.TrimPrefix(recv, "*")
+		if id.Receiver == "" {
+			return FuncID{}, fmt.Errorf("invalid function ID %q: empty receiver", s)
+		}
+		rest = s[idx+end+2:]
+	} else {
+		// Function: pkg.Func, the package path may contain dots
+		idx := strings.LastIndex(s, ".")
+		if idx == -1 {
+			return FuncID{}, fmt.Errorf("invalid function ID %q: missing package", s)
+		}
+		id.Package = s[:idx]
+		rest = s[idx+1:]
+	}
+
+	id.Name, id.Closure, _ = strings.Cut(rest, "$")
+	if id.Package == "" || id.Name == "" {
+		return FuncID{}, fmt.Errorf("invalid function ID %q", s)
+	}
+	if id.Closure != "" {
+		for _, n := range strings.Split(id.Closure, "$") {
+			if _, err := strconv.Atoi(n); err != nil {
+				return FuncID{}, fmt.Errorf("invalid function ID %q: bad closure index %q", s, n)
+			}
+		}
+	}
+	return id, nil
+}
+
+// ssaFuncID returns the ID of an SSA function.
+// Instantiations of generic functions and methods map to their origin.
+// It returns false for synthetic functions that have no source declaration.
+func ssaFuncID(fn *ssa.Function) (FuncID, bool) {
+	// Function literals are identified by their index in the parent
+	var closure []string
+	for fn.Parent() != nil {
+		parent := fn.Parent()
+		index := 0
+		for i, anon := range parent.AnonFuncs {
+			if anon == fn {
+				index = i + 1
+				break
+			}
+		}
+		if index == 0 {
+			return FuncID{}, false
+		}
+		closure = append([]string{strconv.Itoa(index)}, closure...)
+		fn = parent
+	}
+
+	if origin := fn.Origin(); origin != nil {
+		fn = origin
+	}
+	if fn.Pkg == nil {
+		return FuncID{}, false
+	}
+
+	id := FuncID{
+		Package: fn.Pkg.Pkg.Path(),
+		Name:    fn.Name(),
+		Closure: strings.Join(closure, "$"),
+	}
+
+	// SSA numbers multiple init functions as "init#1", "init#2"
+	if strings.HasPrefix(id.Name, "init#") {
+		id.Name = "init"
+	}
+
+	// Handle methods
+	if recv := fn.Signature.Recv(); recv != nil {
+		t := recv.Type()
+		if p, ok := t.(*types.Pointer); ok {
+			id.Pointer = true
+			t = p.Elem()
+		}
+		if named, ok := types.Unalias(t).(*types.Named); ok {
+			id.Receiver = named.Obj().Name()
+		} else {
+			id.Receiver = types.TypeString(t, func(*types.Package) string { return "" })
+		}
+	}
+
+	return id, true
+}
@@ -0,0 +1,126 @@
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseFuncID(t *testing.T) {
+	tests := []struct {
+		s    string
+		want FuncID
+	}{
+		{"io.ReadAll", FuncID{Package: "io", Name: "ReadAll"}},
+		{"net/http.Get", FuncID{Package: "net/http", Name: "Get"}},
+		{"gopkg.in/yaml.v3.Marshal", FuncID{Package: "gopkg.in/yaml.v3", Name: "Marshal"}},
+		{"sync.(*WaitGroup).Add", FuncID{Package: "sync", Receiver: "WaitGroup", Pointer: true, Name: "Add"}},
+		{"time.(Duration).String", FuncID{Package: "time", Receiver: "Duration", Name: "String"}},
+		{"example.com/pkg.Run$1", FuncID{Package: "example.com/pkg", Name: "Run", Closure: "1"}},
+		{"example.com/pkg.(*T).M$2$1", FuncID{Package: "example.com/pkg", Receiver: "T", Pointer: true, Name: "M", Closure: "2$1"}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFuncID(tt.s)
+		if err != nil {
+			t.Errorf("ParseFuncID(%q): %v", tt.s, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFuncID(%q) = %+v, want %+v", tt.s, got, tt.want)
+		}
+		if got.String() != tt.s {
+			t.Errorf("ParseFuncID(%q).String() = %q", tt.s, got.String())
+		}
+	}
+
+	for _, s := range []string{"", "Func", "pkg.(*).M", "pkg.(*T", "pkg.F$x"} {
+		if _, err := ParseFuncID(s); err == nil {
+			t.Errorf("ParseFuncID(%q) should fail", s)
+		}
+	}
+}
+
+func TestFunctionInfo_FuncID(t *testing.T) {
+	tests := []struct {
+		fn   FunctionInfo
+		want string
+	}{
+		{FunctionInfo{Package: "pkg", Name: "F"}, "pkg.F"},
+		{FunctionInfo{Package: "pkg", Name: "M", Receiver: "T"}, "pkg.(T).M"},
+		{FunctionInfo{Package: "pkg", Name: "M", Receiver: "*T"}, "pkg.(*T).M"},
+		{FunctionInfo{Package: "pkg", Name: "Push", Receiver: "*List[E]"}, "pkg.(*List).Push"},
+		{FunctionInfo{Package: "pkg", Name: "Get", Receiver: "Map[K, V]"}, "pkg.(Map).Get"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.fn.ID(); got != tt.want {
+			t.Errorf("ID() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestBuildCallgraph_MethodsAndClosures(t *testing.T) {
+	dir := t.TempDir()
+
+	goMod := `module testpkg
+
+go 1.25
+`
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	goFile := `package testpkg
+
+type T struct{}
+
+func (t *T) Ptr() { t.Val() }
+
+func (t T) Val() {}
+
+func Leaf() {}
+
+func Run() {
+	var t T
+	t.Ptr()
+	func() {
+		func() { Leaf() }()
+	}()
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(goFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pkgs, err := LoadPackages(dir, "./...")
+	if err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+
+	graph := BuildCallgraph(pkgs)
+
+	if !slices.Contains(graph["testpkg.Run"], "testpkg.(*T).Ptr") {
+		t.Errorf("Run should call (*T).Ptr, got %v", graph["testpkg.Run"])
+	}
+	if !slices.Contains(graph["testpkg.(*T).Ptr"], "testpkg.(T).Val") {
+		t.Errorf("(*T).Ptr should call (T).Val, got %v", graph["testpkg.(*T).Ptr"])
+	}
+	if !slices.Contains(graph["testpkg.Run$1$1"], "testpkg.Leaf") {
+		t.Errorf("Run$1$1 should call Leaf, got %v", graph["testpkg.Run$1$1"])
+	}
+
+	// Closures are folded into their enclosing function
+	units := BuildAnalysisUnits(ExtractFunctions(pkgs), graph)
+	callees := make(map[string][]string)
+	for _, unit := range units {
+		callees[unit.ID] = unit.Callees
+	}
+	if got, want := callees["testpkg.Run"], []string{"testpkg.(*T).Ptr", "testpkg.Leaf"}; !slices.Equal(got, want) {
+		t.Errorf("Run callees = %v, want %v", got, want)
+	}
+	if got, want := callees["testpkg.(*T).Ptr"], []string{"testpkg.(T).Val"}; !slices.Equal(got, want) {
+		t.Errorf("(*T).Ptr callees = %v, want %v", got, want)
+	}
+}
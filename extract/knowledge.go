@@ -0,0 +1,38 @@
+package extract
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+)
+
+//go:embed stdlib_knowledge.json
+var stdlibKnowledgeJSON []byte
+
+// Knowledge maps external function IDs to known invariants and pitfalls.
+// IDs use the FuncID string form, e.g. "io.ReadAll" or "sync.(*WaitGroup).Add".
+type Knowledge map[string]KnowledgeEntry
+
+// KnowledgeEntry holds curated facts about a single external function.
+type KnowledgeEntry struct {
+	Invariants []string `json:"invariants,omitempty"`
+	Pitfalls   []string `json:"pitfalls,omitempty"`
+}
+
+// StdlibKnowledge returns the builtin knowledge about standard library functions.
+// The result is a fresh copy that can be extended with Add.
+func StdlibKnowledge() Knowledge {
+	var k Knowledge
+	if err := json.Unmarshal(stdlibKnowledgeJSON, &k); err != nil {
+		panic(fmt.Sprintf("invalid embedded stdlib knowledge: %v", err))
+	}
+	return k
+}
+
+// Add merges entry into the knowledge for the given function ID.
+func (k Knowledge) Add(id string, entry KnowledgeEntry) {
+	existing := k[id]
+	existing.Invariants = append(existing.Invariants, entry.Invariants...)
+	existing.Pitfalls = append(existing.Pitfalls, entry.Pitfalls...)
+	k[id] = existing
+}
@@ -0,0 +1,42 @@
+package extract
+
+import (
+	"testing"
+)
+
+func TestStdlibKnowledge(t *testing.T) {
+	k := StdlibKnowledge()
+
+	entry, ok := k["sync.(*WaitGroup).Add"]
+	if !ok {
+		t.Fatal("missing sync.(*WaitGroup).Add")
+	}
+	if len(entry.Pitfalls) == 0 {
+		t.Error("sync.(*WaitGroup).Add has no pitfalls")
+	}
+
+	// Each call returns an independent copy
+	k.Add("io.ReadAll", KnowledgeEntry{Pitfalls: []string{"custom"}})
+	if len(StdlibKnowledge()["io.ReadAll"].Pitfalls) == len(k["io.ReadAll"].Pitfalls) {
+		t.Error("StdlibKnowledge should return a fresh copy")
+	}
+}
+
+func TestKnowledge_Add(t *testing.T) {
+	k := Knowledge{
+		"pkg.F": {Invariants: []string{"a"}},
+	}
+
+	k.Add("pkg.F", KnowledgeEntry{Invariants: []string{"b"}, Pitfalls: []string{"c"}})
+	k.Add("pkg.G", KnowledgeEntry{Pitfalls: []string{"d"}})
+
+	if got := k["pkg.F"].Invariants; len(got) != 2 {
+		t.Errorf("pkg.F invariants = %v, want [a b]", got)
+	}
+	if got := k["pkg.F"].Pitfalls; len(got) != 1 {
+		t.Errorf("pkg.F pitfalls = %v, want [c]", got)
+	}
+	if got := k["pkg.G"].Pitfalls; len(got) != 1 {
+		t.Errorf("pkg.G pitfalls = %v, want [d]", got)
+	}
+}
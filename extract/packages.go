@@ -2,6 +2,9 @@ package extract
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"golang.org/x/tools/go/packages"
 )
@@ -9,10 +12,73 @@ import (
 // Packages holds loaded package data for reuse across extraction and callgraph building.
 type Packages struct {
 	Pkgs []*packages.Package
+
+	// Filter selects the functions returned by ExtractFunctions.
+	// Excluded functions remain in the callgraph and are described
+	// to their callers like external functions.
+	Filter *Filter
+
+	// Skipped lists packages left out because of errors when
+	// loading with LoadOptions.AllowErrors.
+	Skipped []SkippedPackage
+}
+
+// ModuleRoot returns the directory of the main module of the loaded packages.
+// When packages from several main modules of a workspace are loaded, it
+// returns their closest common directory. It returns "" when the packages
+// are not in a module.
+func (p *Packages) ModuleRoot() string {
+	var root string
+	for _, pkg := range p.Pkgs {
+		if pkg.Module == nil || !pkg.Module.Main || pkg.Module.Dir == "" {
+			continue
+		}
+		if root == "" {
+			root = pkg.Module.Dir
+			continue
+		}
+		for !withinDir(root, pkg.Module.Dir) {
+			parent := filepath.Dir(root)
+			if parent == root {
+				return ""
+			}
+			root = parent
+		}
+	}
+	return root
+}
+
+// withinDir reports whether path is dir or inside dir.
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// SkippedPackage describes a package that could not be analyzed.
+type SkippedPackage struct {
+	Path   string
+	Errors []string
+}
+
+// LoadOptions configures how packages are loaded.
+type LoadOptions struct {
+	Tests     bool     // include _test.go files and external test packages
+	BuildTags []string // build tags, e.g. "integration"
+	GOOS      string   // target operating system, empty uses the default
+	GOARCH    string   // target architecture, empty uses the default
+
+	// AllowErrors skips packages with errors, and packages depending on them,
+	// instead of failing.
+	AllowErrors bool
 }
 
 // LoadPackages loads Go packages once for use by ExtractFunctions and BuildCallgraph.
 func LoadPackages(dir string, patterns ...string) (*Packages, error) {
+	return LoadPackagesWithOptions(dir, LoadOptions{}, patterns...)
+}
+
+// LoadPackagesWithOptions loads Go packages using the given options.
+func LoadPackagesWithOptions(dir string, opts LoadOptions, patterns ...string) (*Packages, error) {
 	cfg := &packages.Config{
 		Mode: packages.NeedName |
 			packages.NeedFiles |
@@ -20,8 +86,23 @@ func LoadPackages(dir string, patterns ...string) (*Packages, error) {
 			packages.NeedTypes |
 			packages.NeedTypesInfo |
 			packages.NeedImports |
-			packages.NeedDeps,
-		Dir: dir,
+			packages.NeedDeps |
+			packages.NeedModule |
+			packages.NeedForTest,
+		Dir:   dir,
+		Tests: opts.Tests,
+	}
+	if len(opts.BuildTags) > 0 {
+		cfg.BuildFlags = []string{"-tags=" + strings.Join(opts.BuildTags, ",")}
+	}
+	if opts.GOOS != "" || opts.GOARCH != "" {
+		cfg.Env = os.Environ()
+		if opts.GOOS != "" {
+			cfg.Env = append(cfg.Env, "GOOS="+opts.GOOS)
+		}
+		if opts.GOARCH != "" {
+			cfg.Env = append(cfg.Env, "GOARCH="+opts.GOARCH)
+		}
 	}
 
 	pkgs, err := packages.Load(cfg, patterns...)
@@ -29,11 +110,83 @@ func LoadPackages(dir string, patterns ...string) (*Packages, error) {
 		return nil, fmt.Errorf("load packages: %w", err)
 	}
 
-	for _, pkg := range pkgs {
-		if len(pkg.Errors) > 0 {
+	result := &Packages{}
+	for _, pkg := range dedupTestVariants(pkgs) {
+		if !opts.AllowErrors && len(pkg.Errors) > 0 {
 			return nil, fmt.Errorf("package %s has errors: %v", pkg.PkgPath, pkg.Errors)
 		}
+		// SSA cannot be built for packages whose dependencies are broken either.
+		if opts.AllowErrors && (len(pkg.Errors) > 0 || pkg.IllTyped) {
+			result.Skipped = append(result.Skipped, skippedPackage(pkg))
+			continue
+		}
+		result.Pkgs = append(result.Pkgs, pkg)
+	}
+
+	return result, nil
+}
+
+// skippedPackage describes why pkg cannot be analyzed.
+func skippedPackage(pkg *packages.Package) SkippedPackage {
+	skipped := SkippedPackage{Path: pkg.PkgPath}
+	for _, err := range pkg.Errors {
+		skipped.Errors = append(skipped.Errors, err.Error())
+	}
+	if len(skipped.Errors) > 0 {
+		return skipped
+	}
+
+	// The package itself is fine, find the broken dependency
+	seen := make(map[string]bool)
+	var visit func(p *packages.Package) string
+	visit = func(p *packages.Package) string {
+		if seen[p.ID] {
+			return ""
+		}
+		seen[p.ID] = true
+		if len(p.Errors) > 0 {
+			return p.PkgPath
+		}
+		for _, imp := range p.Imports {
+			if broken := visit(imp); broken != "" {
+				return broken
+			}
+		}
+		return ""
 	}
+	for _, imp := range pkg.Imports {
+		if broken := visit(imp); broken != "" {
+			skipped.Errors = append(skipped.Errors, "depends on package with errors: "+broken)
+			return skipped
+		}
+	}
+	skipped.Errors = append(skipped.Errors, "depends on package with errors")
+	return skipped
+}
 
-	return &Packages{Pkgs: pkgs}, nil
+// dedupTestVariants removes packages that would be analyzed twice when tests are loaded.
+//
+// For a package "p" with tests go/packages returns "p", its test variant
+// "p [p.test]" containing the same files plus the internal _test.go files,
+// the external test package "p_test [p.test]" and the generated test main "p.test".
+// The test variant replaces "p" and the generated test main is dropped.
+func dedupTestVariants(pkgs []*packages.Package) []*packages.Package {
+	hasTestVariant := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if pkg.ForTest != "" && pkg.ForTest == pkg.PkgPath {
+			hasTestVariant[pkg.PkgPath] = true
+		}
+	}
+
+	result := make([]*packages.Package, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		if pkg.Name == "main" && strings.HasSuffix(pkg.ID, ".test") {
+			continue
+		}
+		if pkg.ForTest == "" && hasTestVariant[pkg.PkgPath] {
+			continue
+		}
+		result = append(result, pkg)
+	}
+	return result
 }
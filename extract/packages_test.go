@@ -0,0 +1,280 @@
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestLoadPackagesWithOptions(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"go.mod": `module testpkg
+
+go 1.25
+`,
+		"main.go": `package testpkg
+
+func A() {}
+`,
+		"main_test.go": `package testpkg
+
+func helper() { A() }
+`,
+		"ext_test.go": `package testpkg_test
+
+func external() {}
+`,
+		"tagged.go": `//go:build special
+
+package testpkg
+
+func Tagged() {}
+`,
+		"os_windows.go": `package testpkg
+
+func Windows() {}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(opts LoadOptions) []string {
+		t.Helper()
+		pkgs, err := LoadPackagesWithOptions(dir, opts, "./...")
+		if err != nil {
+			t.Fatalf("LoadPackagesWithOptions: %v", err)
+		}
+		var names []string
+		for _, fn := range ExtractFunctions(pkgs) {
+			names = append(names, fn.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	if got, want := names(LoadOptions{GOOS: "linux"}), []string{"A"}; !slices.Equal(got, want) {
+		t.Errorf("default: got %v, want %v", got, want)
+	}
+
+	// Test variants must not duplicate the package functions
+	if got, want := names(LoadOptions{GOOS: "linux", Tests: true}), []string{"A", "external", "helper"}; !slices.Equal(got, want) {
+		t.Errorf("tests: got %v, want %v", got, want)
+	}
+
+	if got, want := names(LoadOptions{GOOS: "linux", BuildTags: []string{"special"}}), []string{"A", "Tagged"}; !slices.Equal(got, want) {
+		t.Errorf("build tags: got %v, want %v", got, want)
+	}
+
+	if got, want := names(LoadOptions{GOOS: "windows"}), []string{"A", "Windows"}; !slices.Equal(got, want) {
+		t.Errorf("goos: got %v, want %v", got, want)
+	}
+}
+
+func TestLoadPackagesWithOptions_AllowErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"go.mod": `module testpkg
+
+go 1.25
+`,
+		"ok/ok.go": `package ok
+
+func OK() {}
+`,
+		"broken/broken.go": `package broken
+
+func Broken() int { return "not an int" }
+`,
+		"user/user.go": `package user
+
+import "testpkg/broken"
+
+func User() int { return broken.Broken() }
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := LoadPackages(dir, "./..."); err == nil {
+		t.Fatal("expected error without AllowErrors")
+	}
+
+	pkgs, err := LoadPackagesWithOptions(dir, LoadOptions{AllowErrors: true}, "./...")
+	if err != nil {
+		t.Fatalf("LoadPackagesWithOptions: %v", err)
+	}
+
+	if len(pkgs.Pkgs) != 1 || pkgs.Pkgs[0].PkgPath != "testpkg/ok" {
+		t.Fatalf("got %d packages, want only testpkg/ok", len(pkgs.Pkgs))
+	}
+
+	skipped := make(map[string][]string)
+	for _, s := range pkgs.Skipped {
+		skipped[s.Path] = s.Errors
+	}
+	if len(skipped["testpkg/broken"]) == 0 {
+		t.Errorf("testpkg/broken should be skipped with errors, got %v", pkgs.Skipped)
+	}
+	if errs := skipped["testpkg/user"]; len(errs) != 1 || errs[0] != "depends on package with errors: testpkg/broken" {
+		t.Errorf("testpkg/user errors = %v", errs)
+	}
+
+	// The remaining packages can still be analyzed
+	graph := BuildCallgraph(pkgs)
+	units := BuildAnalysisUnits(ExtractFunctions(pkgs), graph)
+	if len(units) != 1 || units[0].ID != "testpkg/ok.OK" {
+		t.Errorf("got units %v, want [testpkg/ok.OK]", units)
+	}
+}
+
+func TestPackages_ModuleRoot(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"go.work": `go 1.25
+
+use (
+	./services/api
+	./services/worker
+)
+`,
+		"services/api/go.mod": `module example.com/api
+
+go 1.25
+`,
+		"services/api/handler/handler.go": `package handler
+
+func Handle() {}
+`,
+		"services/worker/go.mod": `module example.com/worker
+
+go 1.25
+`,
+		"services/worker/worker.go": `package worker
+
+func Work() {}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Resolve symlinks, such as /tmp on macOS, the same way as the go command
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Workspaces reject -mod=mod, which may be set in the environment
+	t.Setenv("GOFLAGS", "")
+
+	api := filepath.Join(dir, "services", "api")
+	pkgs, err := LoadPackages(api, "./...")
+	if err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	if root := pkgs.ModuleRoot(); root != api {
+		t.Errorf("ModuleRoot = %q, want %q", root, api)
+	}
+
+	pkgs, err = LoadPackages(dir, "example.com/api/...", "example.com/worker")
+	if err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	if root, want := pkgs.ModuleRoot(), filepath.Join(dir, "services"); root != want {
+		t.Errorf("ModuleRoot of workspace = %q, want %q", root, want)
+	}
+}
+
+func TestPackages_CheckFile(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"go.mod": `module testpkg
+
+go 1.25
+`,
+		"dep/dep.go": `package dep
+
+func Value() int { return 1 }
+`,
+		"main.go": `package testpkg
+
+import "testpkg/dep"
+
+func A() int { return dep.Value() + b() }
+`,
+		"b.go": `package testpkg
+
+func b() int { return 2 }
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pkgs, err := LoadPackages(dir, "./...")
+	if err != nil {
+		t.Fatalf("LoadPackages: %v", err)
+	}
+	var filename string
+	for _, pkg := range pkgs.Pkgs {
+		for _, file := range pkg.Syntax {
+			if name := pkg.Fset.Position(file.Pos()).Filename; filepath.Base(name) == "main.go" {
+				filename = name
+			}
+		}
+	}
+
+	tests := []struct {
+		name  string
+		src   string
+		valid bool
+	}{
+		{"unchanged", files["main.go"], true},
+		{"uses other file", "package testpkg\n\nimport \"testpkg/dep\"\n\nfunc A() int { return dep.Value() * b() }\n", true},
+		{"syntax error", "package testpkg\n\nfunc A() int { return }}\n", false},
+		{"type error", "package testpkg\n\nfunc A() int { return \"a\" }\n", false},
+		{"new import", "package testpkg\n\nimport \"testpkg/other\"\n\nfunc A() int { return other.Value() }\n", false},
+	}
+	for _, test := range tests {
+		err := pkgs.CheckFile(filename, []byte(test.src))
+		if test.valid && err != nil {
+			t.Errorf("%s: CheckFile: %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: CheckFile succeeded, want error", test.name)
+		}
+	}
+
+	if err := pkgs.CheckFile(filepath.Join(dir, "missing.go"), nil); err == nil {
+		t.Error("CheckFile of a file outside the packages succeeded")
+	}
+}
@@ -10,6 +10,7 @@ type AnalysisUnit struct {
 	ID        string
 	Functions []*FunctionInfo
 	Callees   []string
+	External  []string // callees outside the analyzed functions
 }
 
 // BuildAnalysisUnits creates analysis units from functions and callgraph
@@ -18,34 +19,13 @@ func BuildAnalysisUnits(funcs []*FunctionInfo, graph map[string][]string) []*Ana
 	// Build function lookup
 	funcMap := make(map[string]*FunctionInfo)
 	for _, f := range funcs {
-		id := f.Package + "." + f.Name
-		if f.Receiver != "" {
-			id = f.Package + ".(" + f.Receiver + ")." + f.Name
-		}
-		funcMap[id] = f
+		funcMap[f.ID()] = f
 	}
 
-	// Filter graph to only include internal functions
-	internalGraph := make(map[string][]string)
-	for caller, callees := range graph {
-		if _, ok := funcMap[caller]; !ok {
-			continue
-		}
-		internalCallees := []string{}
-		for _, callee := range callees {
-			if _, ok := funcMap[callee]; ok {
-				internalCallees = append(internalCallees, callee)
-			}
-		}
-		internalGraph[caller] = internalCallees
-	}
+	// Function literals are analyzed as part of their enclosing function
+	graph = foldClosures(graph)
 
-	// Add nodes with no outgoing edges
-	for id := range funcMap {
-		if _, ok := internalGraph[id]; !ok {
-			internalGraph[id] = []string{}
-		}
-	}
+	internalGraph := internalEdges(funcMap, graph)
 
 	// Compute SCCs
 	sccs := TarjanSCC(internalGraph)
@@ -79,11 +59,7 @@ func BuildAnalysisUnits(funcs []*FunctionInfo, graph map[string][]string) []*Ana
 
 		// Build ID: simpler ID for single-function units
 		if len(unit.Functions) == 1 {
-			f := unit.Functions[0]
-			unit.ID = f.Package + "." + f.Name
-			if f.Receiver != "" {
-				unit.ID = f.Package + ".(" + f.Receiver + ")." + f.Name
-			}
+			unit.ID = unit.Functions[0].ID()
 		} else {
 			// Build ID from sorted function names for multi-function SCCs
 			sortedSCC := make([]string, len(scc))
@@ -103,7 +79,14 @@ func BuildAnalysisUnits(funcs []*FunctionInfo, graph map[string][]string) []*Ana
 	// Second pass: Populate Callees using the unit ID map
 	for i, scc := range sccs {
 		seenCallees := make(map[string]bool)
+		seenExternal := make(map[string]bool)
 		for _, id := range scc {
+			for _, callee := range graph[id] {
+				if _, ok := funcMap[callee]; !ok && !seenExternal[callee] {
+					units[i].External = append(units[i].External, callee)
+					seenExternal[callee] = true
+				}
+			}
 			for _, callee := range internalGraph[id] {
 				calleeUnitIdx := sccMap[callee]
 				if calleeUnitIdx != i {
@@ -119,3 +102,116 @@ func BuildAnalysisUnits(funcs []*FunctionInfo, graph map[string][]string) []*Ana
 
 	return units
 }
+
+// UnitOf returns the unit with the given ID or containing the function with the given ID.
+func UnitOf(units []*AnalysisUnit, id string) *AnalysisUnit {
+	for _, unit := range units {
+		if unit.ID == id {
+			return unit
+		}
+		for _, fn := range unit.Functions {
+			if fn.ID() == id {
+				return unit
+			}
+		}
+	}
+	return nil
+}
+
+// CalleeClosure returns target and all units it transitively calls.
+// units must be in topological order, as returned by BuildAnalysisUnits,
+// and the result keeps that order, ending with target.
+func CalleeClosure(units []*AnalysisUnit, target *AnalysisUnit) []*AnalysisUnit {
+	needed := map[string]bool{target.ID: true}
+	// Callers come after their callees, so walking backwards visits
+	// each unit before any of its callees
+	for i := len(units) - 1; i >= 0; i-- {
+		if !needed[units[i].ID] {
+			continue
+		}
+		for _, callee := range units[i].Callees {
+			needed[callee] = true
+		}
+	}
+
+	var closure []*AnalysisUnit
+	for _, unit := range units {
+		if needed[unit.ID] {
+			closure = append(closure, unit)
+		}
+	}
+	return closure
+}
+
+// InternalCallgraph restricts graph to calls between funcs.
+// Function literals are merged into their enclosing function
+// and every function has an entry, even without callees.
+func InternalCallgraph(funcs []*FunctionInfo, graph map[string][]string) map[string][]string {
+	funcMap := make(map[string]*FunctionInfo)
+	for _, f := range funcs {
+		funcMap[f.ID()] = f
+	}
+	return internalEdges(funcMap, foldClosures(graph))
+}
+
+// internalEdges filters graph to only include functions in funcMap.
+func internalEdges(funcMap map[string]*FunctionInfo, graph map[string][]string) map[string][]string {
+	internalGraph := make(map[string][]string)
+	for caller, callees := range graph {
+		if _, ok := funcMap[caller]; !ok {
+			continue
+		}
+		internalCallees := []string{}
+		for _, callee := range callees {
+			if _, ok := funcMap[callee]; ok {
+				internalCallees = append(internalCallees, callee)
+			}
+		}
+		internalGraph[caller] = internalCallees
+	}
+
+	// Add nodes with no outgoing edges
+	for id := range funcMap {
+		if _, ok := internalGraph[id]; !ok {
+			internalGraph[id] = []string{}
+		}
+	}
+	return internalGraph
+}
+
+// foldClosures merges the edges of function literals into their enclosing function.
+func foldClosures(graph map[string][]string) map[string][]string {
+	enclosing := func(id string) string {
+		fid, err := ParseFuncID(id)
+		if err != nil || fid.Closure == "" {
+			return id
+		}
+		return fid.Enclosing().String()
+	}
+
+	// Iterate in sorted order so that callee order is deterministic
+	callers := make([]string, 0, len(graph))
+	for caller := range graph {
+		callers = append(callers, caller)
+	}
+	sort.Strings(callers)
+
+	folded := make(map[string][]string, len(graph))
+	seen := make(map[string]map[string]bool)
+	for _, caller := range callers {
+		from := enclosing(caller)
+		if _, ok := folded[from]; !ok {
+			folded[from] = []string{}
+			seen[from] = make(map[string]bool)
+		}
+		for _, callee := range graph[caller] {
+			to := enclosing(callee)
+			if to == from || seen[from][to] {
+				continue
+			}
+			seen[from][to] = true
+			folded[from] = append(folded[from], to)
+		}
+	}
+	return folded
+}
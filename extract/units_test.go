@@ -1,6 +1,7 @@
 package extract
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -66,3 +67,68 @@ func TestBuildAnalysisUnits_SCC(t *testing.T) {
 		t.Errorf("first unit should have 2 functions, got %d", len(units[0].Functions))
 	}
 }
+
+func TestBuildAnalysisUnits_External(t *testing.T) {
+	funcs := []*FunctionInfo{
+		{Package: "pkg", Name: "A"},
+		{Package: "pkg", Name: "B"},
+	}
+
+	graph := map[string][]string{
+		"pkg.A": {"pkg.B", "io.ReadAll", "fmt.Println"},
+		"pkg.B": {"io.ReadAll"},
+	}
+
+	units := BuildAnalysisUnits(funcs, graph)
+	if len(units) != 2 {
+		t.Fatalf("got %d units, want 2", len(units))
+	}
+
+	if len(units[0].External) != 1 || units[0].External[0] != "io.ReadAll" {
+		t.Errorf("B external should be [io.ReadAll], got %v", units[0].External)
+	}
+	if len(units[1].External) != 2 {
+		t.Errorf("A external should be [io.ReadAll fmt.Println], got %v", units[1].External)
+	}
+	if len(units[1].Callees) != 1 || units[1].Callees[0] != "pkg.B" {
+		t.Errorf("A callees should be [pkg.B], got %v", units[1].Callees)
+	}
+}
+
+func TestCalleeClosure(t *testing.T) {
+	funcs := []*FunctionInfo{
+		{Package: "pkg", Name: "A"},
+		{Package: "pkg", Name: "B"},
+		{Package: "pkg", Name: "C"},
+		{Package: "pkg", Name: "D"},
+		{Package: "pkg", Name: "E"},
+	}
+
+	// A -> B -> {C <-> D}, E is unrelated
+	graph := map[string][]string{
+		"pkg.A": {"pkg.B"},
+		"pkg.B": {"pkg.C"},
+		"pkg.C": {"pkg.D"},
+		"pkg.D": {"pkg.C"},
+		"pkg.E": {"pkg.A"},
+	}
+
+	units := BuildAnalysisUnits(funcs, graph)
+
+	unit := UnitOf(units, "pkg.D")
+	if unit == nil || unit.ID != "pkg.C+pkg.D" {
+		t.Fatalf("UnitOf(pkg.D) = %v, want unit pkg.C+pkg.D", unit)
+	}
+	if UnitOf(units, "pkg.Missing") != nil {
+		t.Errorf("UnitOf(pkg.Missing) should be nil")
+	}
+
+	closure := CalleeClosure(units, UnitOf(units, "pkg.A"))
+	var ids []string
+	for _, unit := range closure {
+		ids = append(ids, unit.ID)
+	}
+	if got, want := strings.Join(ids, " "), "pkg.C+pkg.D pkg.B pkg.A"; got != want {
+		t.Errorf("CalleeClosure(pkg.A) = %q, want %q", got, want)
+	}
+}
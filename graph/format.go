@@ -0,0 +1,101 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/loov/dreamlint/report"
+)
+
+// severityColors maps severities to fill colors.
+var severityColors = map[report.Severity]string{
+	report.SeverityCritical: "#f4a3a3",
+	report.SeverityHigh:     "#f8c291",
+	report.SeverityMedium:   "#fbe3a1",
+	report.SeverityLow:      "#b8d8f2",
+	report.SeverityInfo:     "#e0e0e0",
+}
+
+// WriteJSON writes the graph as JSON.
+func WriteJSON(w io.Writer, g *Graph) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// WriteDOT writes the graph in Graphviz DOT format.
+func WriteDOT(w io.Writer, g *Graph) error {
+	var b strings.Builder
+	b.WriteString("digraph dreamlint {\n")
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&b, "\t%s [label=%s", dotQuote(node.ID), dotQuote(nodeLabel(node, "\n")))
+		if color, ok := severityColors[node.Severity]; ok {
+			fmt.Fprintf(&b, ", style=filled, fillcolor=%s", dotQuote(color))
+		}
+		b.WriteString("];\n")
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "\t%s -> %s;\n", dotQuote(e.From), dotQuote(e.To))
+	}
+
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart.
+func WriteMermaid(w io.Writer, g *Graph) error {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	// Mermaid node IDs cannot contain most punctuation, use indices instead
+	ids := make(map[string]string, len(g.Nodes))
+	for i, node := range g.Nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(&b, "\t%s[\"%s\"]\n", ids[node.ID], mermaidEscape(nodeLabel(node, "<br/>")))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "\t%s --> %s\n", ids[e.From], ids[e.To])
+	}
+	for _, node := range g.Nodes {
+		if color, ok := severityColors[node.Severity]; ok {
+			fmt.Fprintf(&b, "\tstyle %s fill:%s\n", ids[node.ID], color)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// nodeLabel lists the functions of a node, followed by the issue count.
+func nodeLabel(node *Node, sep string) string {
+	label := strings.Join(node.Functions, sep)
+	if label == "" {
+		label = node.ID
+	}
+	if node.Issues > 0 {
+		label += fmt.Sprintf("%s%d issue(s), %s", sep, node.Issues, node.Severity)
+	}
+	return label
+}
+
+// dotQuote quotes s as a DOT string.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// mermaidEscape escapes characters that terminate a quoted Mermaid label.
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
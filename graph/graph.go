@@ -0,0 +1,186 @@
+// Package graph exports callgraphs and analysis unit DAGs for visualization.
+package graph
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/loov/dreamlint/extract"
+	"github.com/loov/dreamlint/report"
+)
+
+// Graph is a directed graph of functions or analysis units.
+type Graph struct {
+	Nodes []*Node `json:"nodes"`
+	Edges []Edge  `json:"edges"`
+}
+
+// Node is a function or an analysis unit.
+type Node struct {
+	ID        string          `json:"id"`
+	Packages  []string        `json:"packages"`
+	Functions []string        `json:"functions"`
+	Severity  report.Severity `json:"severity,omitempty"`
+	Issues    int             `json:"issues,omitempty"`
+}
+
+// Edge is a call from one node to another.
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// FromCallgraph creates a graph of calls between funcs.
+// graph is the callgraph returned by extract.BuildCallgraph.
+func FromCallgraph(funcs []*extract.FunctionInfo, graph map[string][]string) *Graph {
+	internal := extract.InternalCallgraph(funcs, graph)
+
+	g := &Graph{}
+	for _, fn := range funcs {
+		g.Nodes = append(g.Nodes, &Node{
+			ID:        fn.ID(),
+			Packages:  []string{fn.Package},
+			Functions: []string{fn.ID()},
+		})
+	}
+	for caller, callees := range internal {
+		for _, callee := range callees {
+			g.Edges = append(g.Edges, Edge{From: caller, To: callee})
+		}
+	}
+	g.sort()
+	return g
+}
+
+// FromUnits creates the condensed graph of analysis units.
+func FromUnits(units []*extract.AnalysisUnit) *Graph {
+	g := &Graph{}
+	for _, unit := range units {
+		node := &Node{ID: unit.ID}
+		for _, fn := range unit.Functions {
+			node.Functions = append(node.Functions, fn.ID())
+			if !slices.Contains(node.Packages, fn.Package) {
+				node.Packages = append(node.Packages, fn.Package)
+			}
+		}
+		g.Nodes = append(g.Nodes, node)
+		for _, callee := range unit.Callees {
+			g.Edges = append(g.Edges, Edge{From: unit.ID, To: callee})
+		}
+	}
+	g.sort()
+	return g
+}
+
+// sort orders nodes and edges for deterministic output.
+func (g *Graph) sort() {
+	sort.Slice(g.Nodes, func(i, k int) bool {
+		return g.Nodes[i].ID < g.Nodes[k].ID
+	})
+	sort.Slice(g.Edges, func(i, k int) bool {
+		if g.Edges[i].From != g.Edges[k].From {
+			return g.Edges[i].From < g.Edges[k].From
+		}
+		return g.Edges[i].To < g.Edges[k].To
+	})
+}
+
+// Annotate sets the most severe issue and issue count of each node from a report.
+// Function nodes take the issues of the unit containing the function.
+func (g *Graph) Annotate(r *report.Report) {
+	severities := r.Severities()
+	for unitID, unit := range r.Units {
+		functions := []string{unitID}
+		for _, fn := range unit.Functions {
+			functions = append(functions, fn.ID)
+		}
+		for _, node := range g.Nodes {
+			if !slices.Contains(functions, node.ID) {
+				continue
+			}
+			for _, issue := range unit.Issues {
+				node.Issues++
+				if node.Severity == "" || severities.Compare(issue.Severity, node.Severity) < 0 {
+					node.Severity = issue.Severity
+				}
+			}
+		}
+	}
+}
+
+// FilterPackages keeps nodes with a package matching pattern and the edges between them.
+func (g *Graph) FilterPackages(pattern *extract.Pattern) {
+	g.keep(func(node *Node) bool {
+		for _, pkg := range node.Packages {
+			if pattern.Match(pkg) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Neighborhood keeps nodes within depth calls of the node with the given ID
+// or of the node containing the function with that ID, following calls in
+// both directions. It reports false if there is no such node.
+func (g *Graph) Neighborhood(id string, depth int) bool {
+	var start string
+	for _, node := range g.Nodes {
+		if node.ID == id || slices.Contains(node.Functions, id) {
+			start = node.ID
+			break
+		}
+	}
+	if start == "" {
+		return false
+	}
+
+	neighbors := make(map[string][]string)
+	for _, e := range g.Edges {
+		neighbors[e.From] = append(neighbors[e.From], e.To)
+		neighbors[e.To] = append(neighbors[e.To], e.From)
+	}
+
+	dist := map[string]int{start: 0}
+	queue := []string{start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if dist[current] >= depth {
+			continue
+		}
+		for _, next := range neighbors[current] {
+			if _, seen := dist[next]; !seen {
+				dist[next] = dist[current] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	g.keep(func(node *Node) bool {
+		_, ok := dist[node.ID]
+		return ok
+	})
+	return true
+}
+
+// keep removes nodes not matching fn and edges to or from them.
+func (g *Graph) keep(fn func(node *Node) bool) {
+	kept := make(map[string]bool)
+	nodes := g.Nodes[:0]
+	for _, node := range g.Nodes {
+		if fn(node) {
+			nodes = append(nodes, node)
+			kept[node.ID] = true
+		}
+	}
+	g.Nodes = nodes
+
+	edges := g.Edges[:0]
+	for _, e := range g.Edges {
+		if kept[e.From] && kept[e.To] {
+			edges = append(edges, e)
+		}
+	}
+	g.Edges = edges
+}
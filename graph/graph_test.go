@@ -0,0 +1,210 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/loov/dreamlint/extract"
+	"github.com/loov/dreamlint/report"
+)
+
+func testFuncs() []*extract.FunctionInfo {
+	return []*extract.FunctionInfo{
+		{Package: "a", Name: "A"},
+		{Package: "a", Name: "B"},
+		{Package: "b", Name: "C"},
+		{Package: "b", Name: "D"},
+	}
+}
+
+func testCallgraph() map[string][]string {
+	return map[string][]string{
+		"a.A": {"a.B", "fmt.Println"},
+		"a.B": {"b.C"},
+		"b.C": {"b.D"},
+		"b.D": {"b.C"},
+	}
+}
+
+func edgeStrings(g *Graph) []string {
+	var edges []string
+	for _, e := range g.Edges {
+		edges = append(edges, e.From+"->"+e.To)
+	}
+	return edges
+}
+
+func nodeIDs(g *Graph) []string {
+	var ids []string
+	for _, node := range g.Nodes {
+		ids = append(ids, node.ID)
+	}
+	return ids
+}
+
+func TestFromCallgraph(t *testing.T) {
+	g := FromCallgraph(testFuncs(), testCallgraph())
+
+	if got, want := strings.Join(nodeIDs(g), " "), "a.A a.B b.C b.D"; got != want {
+		t.Errorf("nodes = %q, want %q", got, want)
+	}
+	if got, want := strings.Join(edgeStrings(g), " "), "a.A->a.B a.B->b.C b.C->b.D b.D->b.C"; got != want {
+		t.Errorf("edges = %q, want %q", got, want)
+	}
+}
+
+func TestFromUnits(t *testing.T) {
+	units := extract.BuildAnalysisUnits(testFuncs(), testCallgraph())
+	g := FromUnits(units)
+
+	if got, want := strings.Join(nodeIDs(g), " "), "a.A a.B b.C+b.D"; got != want {
+		t.Errorf("nodes = %q, want %q", got, want)
+	}
+	if got, want := strings.Join(edgeStrings(g), " "), "a.A->a.B a.B->b.C+b.D"; got != want {
+		t.Errorf("edges = %q, want %q", got, want)
+	}
+
+	scc := g.Nodes[2]
+	if got := strings.Join(scc.Functions, " "); !strings.Contains(got, "b.C") || !strings.Contains(got, "b.D") {
+		t.Errorf("unit functions = %q, want b.C and b.D", got)
+	}
+	if len(scc.Packages) != 1 || scc.Packages[0] != "b" {
+		t.Errorf("unit packages = %v, want [b]", scc.Packages)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	rpt := report.NewReport()
+	rpt.Units["b.C+b.D"] = report.UnitReport{
+		Functions: []report.FunctionInfo{{ID: "b.C"}, {ID: "b.D"}},
+	}
+	rpt.AddIssue("b.C+b.D", report.Issue{Severity: report.SeverityLow})
+	rpt.AddIssue("b.C+b.D", report.Issue{Severity: report.SeverityHigh})
+
+	units := FromUnits(extract.BuildAnalysisUnits(testFuncs(), testCallgraph()))
+	units.Annotate(rpt)
+	for _, node := range units.Nodes {
+		if node.ID == "b.C+b.D" {
+			if node.Issues != 2 || node.Severity != report.SeverityHigh {
+				t.Errorf("unit annotation = %d %s, want 2 high", node.Issues, node.Severity)
+			}
+		} else if node.Issues != 0 {
+			t.Errorf("%s: got %d issues, want 0", node.ID, node.Issues)
+		}
+	}
+
+	calls := FromCallgraph(testFuncs(), testCallgraph())
+	calls.Annotate(rpt)
+	for _, node := range calls.Nodes {
+		want := 0
+		if node.ID == "b.C" || node.ID == "b.D" {
+			want = 2
+		}
+		if node.Issues != want {
+			t.Errorf("%s: got %d issues, want %d", node.ID, node.Issues, want)
+		}
+	}
+}
+
+func TestFilterPackages(t *testing.T) {
+	g := FromCallgraph(testFuncs(), testCallgraph())
+
+	pattern, err := extract.CompilePattern("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.FilterPackages(pattern)
+
+	if got, want := strings.Join(nodeIDs(g), " "), "b.C b.D"; got != want {
+		t.Errorf("nodes = %q, want %q", got, want)
+	}
+	if got, want := strings.Join(edgeStrings(g), " "), "b.C->b.D b.D->b.C"; got != want {
+		t.Errorf("edges = %q, want %q", got, want)
+	}
+}
+
+func TestNeighborhood(t *testing.T) {
+	tests := []struct {
+		focus string
+		depth int
+		want  string
+	}{
+		{"a.A", 0, "a.A"},
+		{"a.A", 1, "a.A a.B"},
+		{"a.B", 1, "a.A a.B b.C"},
+		{"b.D", 2, "a.B b.C b.D"},
+	}
+
+	for _, test := range tests {
+		g := FromCallgraph(testFuncs(), testCallgraph())
+		if !g.Neighborhood(test.focus, test.depth) {
+			t.Errorf("Neighborhood(%q, %d) did not find the node", test.focus, test.depth)
+			continue
+		}
+		if got := strings.Join(nodeIDs(g), " "); got != test.want {
+			t.Errorf("Neighborhood(%q, %d) = %q, want %q", test.focus, test.depth, got, test.want)
+		}
+	}
+
+	// Functions are found inside their unit
+	g := FromUnits(extract.BuildAnalysisUnits(testFuncs(), testCallgraph()))
+	if !g.Neighborhood("b.D", 0) {
+		t.Fatal("Neighborhood did not find b.D in its unit")
+	}
+	if got, want := strings.Join(nodeIDs(g), " "), "b.C+b.D"; got != want {
+		t.Errorf("nodes = %q, want %q", got, want)
+	}
+
+	if g.Neighborhood("missing.F", 1) {
+		t.Error("Neighborhood found a missing function")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	g := FromCallgraph(testFuncs(), testCallgraph())
+	g.Nodes[0].Severity = report.SeverityCritical
+	g.Nodes[0].Issues = 1
+
+	var dot bytes.Buffer
+	if err := WriteDOT(&dot, g); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"digraph dreamlint {",
+		`"a.A" -> "a.B";`,
+		`"a.A" [label="a.A\n1 issue(s), critical", style=filled, fillcolor="#f4a3a3"];`,
+	} {
+		if !strings.Contains(dot.String(), want) {
+			t.Errorf("DOT output missing %q:\n%s", want, dot.String())
+		}
+	}
+
+	var mermaid bytes.Buffer
+	if err := WriteMermaid(&mermaid, g); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"flowchart LR",
+		`n0["a.A<br/>1 issue(s), critical"]`,
+		"n0 --> n1",
+		"style n0 fill:#f4a3a3",
+	} {
+		if !strings.Contains(mermaid.String(), want) {
+			t.Errorf("Mermaid output missing %q:\n%s", want, mermaid.String())
+		}
+	}
+
+	var data bytes.Buffer
+	if err := WriteJSON(&data, g); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Graph
+	if err := json.Unmarshal(data.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Nodes) != 4 || len(decoded.Edges) != 4 {
+		t.Errorf("JSON round trip = %d nodes, %d edges, want 4, 4", len(decoded.Nodes), len(decoded.Edges))
+	}
+}
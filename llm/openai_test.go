@@ -0,0 +1,37 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestOpenAIClient_Models(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q, want %q", got, "Bearer secret")
+		}
+		w.Write([]byte(`{"object": "list", "data": [{"id": "model-a"}, {"id": "model-b"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewOpenAIClient(server.URL+"/v1", "secret")
+	models, err := client.Models(context.Background())
+	if err != nil {
+		t.Fatalf("Models: %v", err)
+	}
+	if want := []string{"model-a", "model-b"}; !slices.Equal(models, want) {
+		t.Errorf("Models = %v, want %v", models, want)
+	}
+
+	client = NewOpenAIClient(server.URL+"/missing", "secret")
+	if _, err := client.Models(context.Background()); err == nil {
+		t.Error("Models should fail for a missing endpoint")
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/loov/dreamlint/analyze"
+	"github.com/loov/dreamlint/cache"
+	"github.com/loov/dreamlint/config"
+	"github.com/loov/dreamlint/extract"
+	"github.com/loov/dreamlint/llm"
+	"github.com/loov/dreamlint/report"
+	"github.com/loov/dreamlint/triage"
+)
+
+// loaded holds the packages, callgraph and analysis units shared by commands.
+type loaded struct {
+	pkgs          *extract.Packages
+	funcs         []*extract.FunctionInfo
+	graph         map[string][]string
+	externalFuncs map[string]*extract.ExternalFunc
+	units         []*extract.AnalysisUnit
+}
+
+// loadUnits loads packages from dir and builds analysis units, writing
+// progress to log.
+func loadUnits(log io.Writer, cfg *config.Config, allowErrors bool, dir string, patterns []string) (*loaded, error) {
+	// Load packages once
+	fmt.Fprintln(log, "Loading packages...")
+	pkgs, err := extract.LoadPackagesWithOptions(dir, extract.LoadOptions{
+		Tests:       cfg.Packages.Tests,
+		BuildTags:   cfg.Packages.BuildTags,
+		GOOS:        cfg.Packages.GOOS,
+		GOARCH:      cfg.Packages.GOARCH,
+		AllowErrors: allowErrors,
+	}, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("load packages: %w", err)
+	}
+	for _, skipped := range pkgs.Skipped {
+		fmt.Fprintf(log, "Warning: skipping package %s:\n", skipped.Path)
+		for _, msg := range skipped.Errors {
+			fmt.Fprintf(log, "    %s\n", msg)
+		}
+	}
+	pkgs.Filter, err = buildFilter(cfg.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("build filter: %w", err)
+	}
+
+	// Extract functions
+	fmt.Fprintln(log, "Extracting functions...")
+	funcs := extract.ExtractFunctions(pkgs)
+	fmt.Fprintf(log, "Found %d functions\n", len(funcs))
+
+	// Build callgraph
+	fmt.Fprintln(log, "Building callgraph...")
+	graph := extract.BuildCallgraph(pkgs)
+
+	// Extract external function info
+	fmt.Fprintln(log, "Extracting external functions...")
+	knowledge := extract.StdlibKnowledge()
+	for id, k := range cfg.Knowledge {
+		knowledge.Add(id, extract.KnowledgeEntry{
+			Invariants: k.Invariants,
+			Pitfalls:   k.Pitfalls,
+		})
+	}
+	externalFuncs := extract.ExtractExternalFuncs(pkgs, graph, knowledge)
+	fmt.Fprintf(log, "Found %d external functions\n", len(externalFuncs))
+
+	// Build analysis units
+	fmt.Fprintln(log, "Building analysis units...")
+	units := extract.BuildAnalysisUnits(funcs, graph)
+	fmt.Fprintf(log, "Created %d analysis units\n", len(units))
+
+	return &loaded{
+		pkgs:          pkgs,
+		funcs:         funcs,
+		graph:         graph,
+		externalFuncs: externalFuncs,
+		units:         units,
+	}, nil
+}
+
+// buildFilter compiles the filter patterns from config.
+func buildFilter(cfg config.FilterConfig) (*extract.Filter, error) {
+	packages, err := extract.CompilePatterns(cfg.Packages.Include, cfg.Packages.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("packages: %w", err)
+	}
+	files, err := extract.CompilePatterns(cfg.Files.Include, cfg.Files.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("files: %w", err)
+	}
+	functions, err := extract.CompilePatterns(cfg.Functions.Include, cfg.Functions.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("functions: %w", err)
+	}
+	return &extract.Filter{
+		Packages:  packages,
+		Files:     files,
+		Functions: functions,
+		Generated: cfg.Generated,
+	}, nil
+}
+
+// openCache returns the summary cache, or nil when caching is disabled.
+func openCache(cfg *config.Config) *cache.Cache {
+	if !cfg.Cache.Enabled {
+		return nil
+	}
+	return cache.New(cfg.Cache.Dir)
+}
+
+// reportSummaries returns the unit summaries of rpt for use as callee summaries.
+func reportSummaries(rpt *report.Report) map[string]*analyze.SummaryResponse {
+	summaries := make(map[string]*analyze.SummaryResponse)
+	for unitID, unitReport := range rpt.Units {
+		summaries[unitID] = &analyze.SummaryResponse{
+			Purpose:    unitReport.Summary.Purpose,
+			Behavior:   unitReport.Summary.Behavior,
+			Invariants: unitReport.Summary.Invariants,
+			Security:   unitReport.Summary.Security,
+		}
+	}
+	return summaries
+}
+
+// reportPaths returns the paths that make report file names relative to
+// root, or to the working directory when root is empty. The prefix has to
+// be a suffix of the root, otherwise the relative paths could not be
+// resolved from the report.
+func reportPaths(root, prefix string) (report.Paths, error) {
+	paths := report.Paths{Root: root, Prefix: prefix}
+	if paths.Root == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return report.Paths{}, fmt.Errorf("get working directory: %w", err)
+		}
+		paths.Root = wd
+	}
+	if paths.SourceRoot() == "" {
+		return report.Paths{}, fmt.Errorf("path prefix %q is not a suffix of the module root %s", prefix, paths.Root)
+	}
+	return paths, nil
+}
+
+// loadPipeline creates an analysis pipeline and loads its prompts,
+// overriding the builtin prompts with promptsDir when set.
+func loadPipeline(cfg *config.Config, client llm.Client, externalFuncs map[string]*extract.ExternalFunc, promptsDir string) (*analyze.Pipeline, error) {
+	pipeline := analyze.NewPipeline(cfg, openCache(cfg), client, externalFuncs)
+	if promptsDir != "" {
+		pipeline.SetPromptsFS(os.DirFS(promptsDir))
+	}
+	if err := pipeline.LoadPrompts(); err != nil {
+		return nil, fmt.Errorf("load prompts: %w", err)
+	}
+	if cfg.Triage.File != "" && cfg.Examples.Max > 0 {
+		decisions, err := triage.Load(cfg.Triage.File)
+		if err != nil {
+			return nil, fmt.Errorf("load triage decisions: %w", err)
+		}
+		pipeline.SetExamples(triageExamples(decisions))
+	}
+	return pipeline, nil
+}
+
+// triageExamples returns the confirmed issues and false positives of the
+// triage decisions as few-shot examples.
+func triageExamples(decisions *triage.Decisions) []analyze.Example {
+	var examples []analyze.Example
+	for _, entry := range decisions.Entries {
+		if entry.Decision != triage.TruePositive && entry.Decision != triage.FalsePositive {
+			continue
+		}
+		examples = append(examples, analyze.Example{
+			Unit:     entry.Unit,
+			Category: entry.Issue.Category,
+			Code:     entry.Issue.Snippet,
+			Message:  entry.Issue.Message,
+			Valid:    entry.Decision == triage.TruePositive,
+		})
+	}
+	return examples
+}
+
+// cachedSummaries returns the cached summaries of units, which must be in
+// analysis order. Summaries already in known are kept and used as callee
+// summaries. A unit is only looked up when all its callees have a summary,
+// because the cache key includes the callee summaries.
+func cachedSummaries(pipeline *analyze.Pipeline, units []*extract.AnalysisUnit, known map[string]*analyze.SummaryResponse) map[string]*analyze.SummaryResponse {
+	summaries := make(map[string]*analyze.SummaryResponse, len(known))
+	for id, summary := range known {
+		summaries[id] = summary
+	}
+
+	for _, unit := range units {
+		if _, ok := summaries[unit.ID]; ok || !calleesSummarized(unit, summaries) {
+			continue
+		}
+		if summary := pipeline.CachedSummary(unit, summaries); summary != nil {
+			summaries[unit.ID] = summary
+		}
+	}
+	return summaries
+}
+
+// calleesSummarized reports whether all callees of unit have a summary.
+func calleesSummarized(unit *extract.AnalysisUnit, summaries map[string]*analyze.SummaryResponse) bool {
+	for _, callee := range unit.Callees {
+		if _, ok := summaries[callee]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestReportPaths(t *testing.T) {
+	root := filepath.FromSlash("/src/repo/services/api")
+
+	paths, err := reportPaths(root, "services/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := paths.SourceRoot(), filepath.FromSlash("/src/repo"); got != want {
+		t.Errorf("SourceRoot() = %q, want %q", got, want)
+	}
+
+	if _, err := reportPaths(root, "services/web"); err == nil {
+		t.Error("expected an error for a prefix that is not a suffix of the root")
+	}
+}
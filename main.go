@@ -12,7 +12,21 @@ import (
 func main() {
 	ctx := context.Background()
 	ok, err := clingy.Environment{}.Run(ctx, func(cmds clingy.Commands) {
+		cmds.New("init", "create a starter config and optionally copy the builtin prompts", new(cmdInit))
 		cmds.New("run", "analyze packages for issues", new(cmdRun))
+		cmds.New("graph", "export the callgraph or analysis unit graph", new(cmdGraph))
+		cmds.New("units", "list analysis units in analysis order", new(cmdUnits))
+		cmds.New("explain", "analyze a single function and its callees", new(cmdExplain))
+		cmds.New("fix", "apply validated fixes of the issues in a report", new(cmdFix))
+		cmds.New("diff", "compare the issues and summaries of two reports", new(cmdDiff))
+		cmds.New("triage", "review the issues of a report and record decisions about them", new(cmdTriage))
+		cmds.New("eval", "measure how well the passes find the expected issues of a corpus", new(cmdEval))
+		cmds.New("prompt", "render prompts without calling the LLM", new(cmdPrompt))
+		cmds.Group("config", "inspect the configuration", func() {
+			cmds.New("show", "print the resolved config with API keys redacted", new(cmdConfigShow))
+			cmds.New("validate", "check that the config and its prompts load", new(cmdConfigValidate))
+			cmds.New("schema", "print the config schema", new(cmdConfigSchema))
+		})
 	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
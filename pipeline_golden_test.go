@@ -97,7 +97,7 @@ func runPipelineGoldenTest(t *testing.T, txtarFile string) {
 
 	// Build callgraph and units
 	graph := extract.BuildCallgraph(pkgs)
-	externalFuncs := extract.ExtractExternalFuncs(pkgs, graph)
+	externalFuncs := extract.ExtractExternalFuncs(pkgs, graph, nil)
 	units := extract.BuildAnalysisUnits(funcs, graph)
 
 	// Create mock client with summary response
@@ -0,0 +1,173 @@
+package report
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// Diff is the difference between the issues and summaries of two reports
+type Diff struct {
+	Units   []UnitDiff  `json:"units"`
+	Summary DiffSummary `json:"summary"`
+}
+
+// Statuses of a unit that is only in one of the compared reports.
+const (
+	UnitAdded   = "added"
+	UnitRemoved = "removed"
+)
+
+// UnitDiff holds the changes of a single unit
+type UnitDiff struct {
+	Unit string `json:"unit"`
+	// Status is UnitAdded or UnitRemoved when the unit is only in one of
+	// the reports, and empty otherwise.
+	Status  string         `json:"status,omitempty"`
+	Passes  []PassDiff     `json:"passes,omitempty"`
+	Summary *SummaryChange `json:"summary,omitempty"`
+}
+
+// PassDiff holds the changed issues of an analysis pass
+type PassDiff struct {
+	Pass     string        `json:"pass"`
+	Added    []Issue       `json:"added,omitempty"`
+	Removed  []Issue       `json:"removed,omitempty"`
+	Severity []IssueChange `json:"severity,omitempty"` // issues with a changed severity
+}
+
+// IssueChange is an issue reported in both reports
+type IssueChange struct {
+	Old Issue `json:"old"`
+	New Issue `json:"new"`
+}
+
+// SummaryChange is a unit summary that differs between the reports
+type SummaryChange struct {
+	Old FunctionSummary `json:"old"`
+	New FunctionSummary `json:"new"`
+}
+
+// DiffSummary counts the changes
+type DiffSummary struct {
+	Added     int `json:"added"`
+	Removed   int `json:"removed"`
+	Severity  int `json:"severity_changed"`
+	Unchanged int `json:"unchanged"`
+	Summaries int `json:"summaries_changed"`
+}
+
+// Compare returns the difference from old to newer. Issues are matched by
+// their fingerprint, so moved code is not reported as a change.
+func Compare(old, newer *Report) *Diff {
+	diff := &Diff{}
+
+	ids := make([]string, 0, len(old.Units)+len(newer.Units))
+	for id := range old.Units {
+		ids = append(ids, id)
+	}
+	for id := range newer.Units {
+		if _, ok := old.Units[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		oldUnit, inOld := old.Units[id]
+		newUnit, inNew := newer.Units[id]
+
+		unit := UnitDiff{Unit: id}
+		switch {
+		case !inOld:
+			unit.Status = UnitAdded
+		case !inNew:
+			unit.Status = UnitRemoved
+		case !sameSummary(oldUnit.Summary, newUnit.Summary):
+			unit.Summary = &SummaryChange{Old: oldUnit.Summary, New: newUnit.Summary}
+			diff.Summary.Summaries++
+		}
+
+		unit.Passes = comparePasses(id, oldUnit.Issues, newUnit.Issues, &diff.Summary)
+		if unit.Status != "" || unit.Summary != nil || len(unit.Passes) > 0 {
+			diff.Units = append(diff.Units, unit)
+		}
+	}
+	return diff
+}
+
+// comparePasses matches the issues of a unit and returns the changes by pass.
+func comparePasses(unitID string, old, newer []Issue, summary *DiffSummary) []PassDiff {
+	// Issues with the same fingerprint are matched in order of position
+	unmatched := make(map[string][]Issue)
+	for _, issue := range sortedIssues(old) {
+		fingerprint := issue.Fingerprint(unitID)
+		unmatched[fingerprint] = append(unmatched[fingerprint], issue)
+	}
+
+	passes := make(map[string]*PassDiff)
+	pass := func(name string) *PassDiff {
+		if passes[name] == nil {
+			passes[name] = &PassDiff{Pass: name}
+		}
+		return passes[name]
+	}
+
+	for _, issue := range sortedIssues(newer) {
+		fingerprint := issue.Fingerprint(unitID)
+		candidates := unmatched[fingerprint]
+		if len(candidates) == 0 {
+			pass(issue.Category).Added = append(pass(issue.Category).Added, issue)
+			summary.Added++
+			continue
+		}
+		match := candidates[0]
+		unmatched[fingerprint] = candidates[1:]
+		if match.Severity != issue.Severity {
+			pass(issue.Category).Severity = append(pass(issue.Category).Severity, IssueChange{Old: match, New: issue})
+			summary.Severity++
+			continue
+		}
+		summary.Unchanged++
+	}
+
+	for _, issue := range sortedIssues(old) {
+		fingerprint := issue.Fingerprint(unitID)
+		// The remaining candidates are the last ones of the fingerprint
+		if candidates := unmatched[fingerprint]; len(candidates) > 0 {
+			unmatched[fingerprint] = candidates[1:]
+			pass(issue.Category).Removed = append(pass(issue.Category).Removed, candidates[0])
+			summary.Removed++
+		}
+	}
+
+	result := make([]PassDiff, 0, len(passes))
+	for _, pass := range passes {
+		result = append(result, *pass)
+	}
+	slices.SortFunc(result, func(a, b PassDiff) int { return cmp.Compare(a.Pass, b.Pass) })
+	return result
+}
+
+// sortedIssues returns issues sorted by position.
+func sortedIssues(issues []Issue) []Issue {
+	issues = slices.Clone(issues)
+	slices.SortStableFunc(issues, func(a, b Issue) int {
+		return cmp.Or(
+			cmp.Compare(a.Position.Filename, b.Position.Filename),
+			cmp.Compare(a.Position.Line, b.Position.Line),
+		)
+	})
+	return issues
+}
+
+// sameSummary reports whether the summaries are equal, ignoring whitespace.
+func sameSummary(a, b FunctionSummary) bool {
+	same := func(x, y string) bool {
+		return strings.Join(strings.Fields(x), " ") == strings.Join(strings.Fields(y), " ")
+	}
+	return same(a.Purpose, b.Purpose) &&
+		same(a.Behavior, b.Behavior) &&
+		slices.EqualFunc(a.Invariants, b.Invariants, same) &&
+		slices.EqualFunc(a.Security, b.Security, same)
+}
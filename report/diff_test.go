@@ -0,0 +1,81 @@
+package report
+
+import (
+	"go/token"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	issue := func(line int, severity Severity, category, snippet string) Issue {
+		return Issue{
+			Position: token.Position{Filename: "a.go", Line: line},
+			Severity: severity,
+			Category: category,
+			Message:  snippet + " is wrong",
+			Snippet:  snippet,
+		}
+	}
+
+	old := NewReport()
+	old.AddUnit("pkg.A", UnitReport{
+		Summary: FunctionSummary{Purpose: "does  a"},
+		Issues: []Issue{
+			issue(3, SeverityHigh, "correctness", "x := 1"),
+			issue(4, SeverityLow, "correctness", "y := 2"),
+			issue(5, SeverityLow, "security", "z := 3"),
+			issue(6, SeverityLow, "security", "z := 3"),
+		},
+	})
+	old.AddUnit("pkg.B", UnitReport{Issues: []Issue{issue(10, SeverityLow, "correctness", "b()")}})
+	old.AddUnit("pkg.Same", UnitReport{Summary: FunctionSummary{Purpose: "same"}})
+
+	newer := NewReport()
+	newer.AddUnit("pkg.A", UnitReport{
+		Summary: FunctionSummary{Purpose: "does a", Behavior: "changed"},
+		Issues: []Issue{
+			// Moved by a line, which is not a change
+			issue(4, SeverityHigh, "correctness", "x := 1"),
+			issue(5, SeverityMedium, "correctness", "y := 2"),
+			issue(6, SeverityLow, "security", "z := 3"),
+			issue(7, SeverityLow, "security", "w := 4"),
+		},
+	})
+	newer.AddUnit("pkg.C", UnitReport{Issues: []Issue{issue(20, SeverityLow, "correctness", "c()")}})
+	newer.AddUnit("pkg.Same", UnitReport{Summary: FunctionSummary{Purpose: "same"}})
+
+	diff := Compare(old, newer)
+
+	want := DiffSummary{Added: 2, Removed: 2, Severity: 1, Unchanged: 2, Summaries: 1}
+	if diff.Summary != want {
+		t.Errorf("summary = %+v, want %+v", diff.Summary, want)
+	}
+	if len(diff.Units) != 3 {
+		t.Fatalf("got %d units, want 3: %+v", len(diff.Units), diff.Units)
+	}
+
+	a, b, c := diff.Units[0], diff.Units[1], diff.Units[2]
+	if a.Unit != "pkg.A" || a.Status != "" || a.Summary == nil || a.Summary.New.Behavior != "changed" {
+		t.Errorf("unit A = %+v", a)
+	}
+	if len(a.Passes) != 2 || a.Passes[0].Pass != "correctness" || a.Passes[1].Pass != "security" {
+		t.Fatalf("unit A passes = %+v", a.Passes)
+	}
+	correctness, security := a.Passes[0], a.Passes[1]
+	if len(correctness.Added) != 0 || len(correctness.Removed) != 0 || len(correctness.Severity) != 1 {
+		t.Errorf("correctness = %+v", correctness)
+	} else if change := correctness.Severity[0]; change.Old.Severity != SeverityLow || change.New.Severity != SeverityMedium {
+		t.Errorf("severity change = %+v", change)
+	}
+	// Of the duplicate issues, one is matched and the later one removed
+	if len(security.Added) != 1 || security.Added[0].Snippet != "w := 4" ||
+		len(security.Removed) != 1 || security.Removed[0].Position.Line != 6 {
+		t.Errorf("security = %+v", security)
+	}
+
+	if b.Unit != "pkg.B" || b.Status != UnitRemoved || len(b.Passes) != 1 || len(b.Passes[0].Removed) != 1 {
+		t.Errorf("unit B = %+v", b)
+	}
+	if c.Unit != "pkg.C" || c.Status != UnitAdded || len(c.Passes) != 1 || len(c.Passes[0].Added) != 1 {
+		t.Errorf("unit C = %+v", c)
+	}
+}
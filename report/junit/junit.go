@@ -0,0 +1,239 @@
+// Package junit provides JUnit XML output for reports, for CI systems that
+// show test results.
+package junit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/loov/dreamlint/report"
+)
+
+// TestSuites is the root JUnit element
+type TestSuites struct {
+	XMLName  xml.Name    `xml:"testsuites"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr,omitempty"`
+	Suites   []TestSuite `xml:"testsuite"`
+}
+
+// TestSuite groups the test cases of a package
+type TestSuite struct {
+	Name      string     `xml:"name,attr"`
+	Tests     int        `xml:"tests,attr"`
+	Failures  int        `xml:"failures,attr"`
+	Skipped   int        `xml:"skipped,attr"`
+	Timestamp string     `xml:"timestamp,attr,omitempty"`
+	Cases     []TestCase `xml:"testcase"`
+}
+
+// TestCase is a unit or a package
+type TestCase struct {
+	Name      string    `xml:"name,attr"`
+	ClassName string    `xml:"classname,attr"`
+	File      string    `xml:"file,attr,omitempty"`
+	Line      int       `xml:"line,attr,omitempty"`
+	Failures  []Failure `xml:"failure"`
+	Skipped   *Skipped  `xml:"skipped"`
+	SystemOut *Output   `xml:"system-out"`
+}
+
+// Failure is an issue
+type Failure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",cdata"`
+}
+
+// Output is the text output of a test case
+type Output struct {
+	Text string `xml:",cdata"`
+}
+
+// Skipped marks a package that was not analyzed
+type Skipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// Options configures the test cases and failures
+type Options struct {
+	// MinSeverity is the least severe issue reported as a failure, empty
+	// reports all issues as failures. Less severe issues are listed in the
+	// output of the test case.
+	MinSeverity report.Severity
+	// ByPackage makes every package a test case, instead of every unit.
+	ByPackage bool
+}
+
+// Write renders the report as JUnit XML
+func Write(r *report.Report, opts Options) ([]byte, error) {
+	data, err := xml.MarshalIndent(FromReport(r, opts), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// WriteFile writes the JUnit report to a file
+func WriteFile(r *report.Report, path string, opts Options) error {
+	data, err := Write(r, opts)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// FromReport converts a report to JUnit test suites, one for every package.
+// When ByPackage is set, the packages are the test cases of a single suite.
+func FromReport(r *report.Report, opts Options) *TestSuites {
+	severities := r.Severities()
+	failing := func(issue report.Issue) bool {
+		return opts.MinSeverity == "" || severities.AtLeast(issue.Severity, opts.MinSeverity)
+	}
+
+	// Group units by package, sorted for deterministic output
+	byPackage := make(map[string][]string)
+	for id, unit := range r.Units {
+		pkg := unitPackage(id, unit)
+		byPackage[pkg] = append(byPackage[pkg], id)
+	}
+	packages := make([]string, 0, len(byPackage))
+	for pkg, ids := range byPackage {
+		slices.Sort(ids)
+		packages = append(packages, pkg)
+	}
+	slices.Sort(packages)
+
+	suites := &TestSuites{Name: "dreamlint"}
+	if !r.Metadata.CompletedAt.IsZero() && !r.Metadata.GeneratedAt.IsZero() {
+		suites.Time = fmt.Sprintf("%.3f", r.Metadata.CompletedAt.Sub(r.Metadata.GeneratedAt).Seconds())
+	}
+	timestamp := ""
+	if !r.Metadata.GeneratedAt.IsZero() {
+		timestamp = r.Metadata.GeneratedAt.UTC().Format(time.RFC3339)
+	}
+
+	var packageCases []TestCase
+	for _, pkg := range packages {
+		var cases []TestCase
+		for _, id := range byPackage[pkg] {
+			unit := r.Units[id]
+			testCase := TestCase{
+				Name:      strings.ReplaceAll(id, pkg+".", ""),
+				ClassName: pkg,
+			}
+			if len(unit.Functions) > 0 {
+				testCase.File = unit.Functions[0].Position.Filename
+				testCase.Line = unit.Functions[0].Position.Line
+			}
+			addIssues(&testCase, unit.Issues, failing)
+			cases = append(cases, testCase)
+		}
+
+		if opts.ByPackage {
+			testCase := TestCase{Name: pkg, ClassName: pkg}
+			var out strings.Builder
+			for _, unitCase := range cases {
+				testCase.Failures = append(testCase.Failures, unitCase.Failures...)
+				if unitCase.SystemOut != nil {
+					out.WriteString(unitCase.SystemOut.Text)
+				}
+			}
+			if out.Len() > 0 {
+				testCase.SystemOut = &Output{Text: out.String()}
+			}
+			packageCases = append(packageCases, testCase)
+			continue
+		}
+		suites.Suites = append(suites.Suites, newSuite(pkg, timestamp, cases))
+	}
+
+	// Packages left out because of errors are skipped test cases
+	for _, skipped := range r.Metadata.SkippedPackages {
+		testCase := TestCase{
+			Name:      skipped.Package,
+			ClassName: skipped.Package,
+			Skipped:   &Skipped{Message: "not analyzed: " + strings.Join(skipped.Errors, "; ")},
+		}
+		if opts.ByPackage {
+			packageCases = append(packageCases, testCase)
+			continue
+		}
+		suites.Suites = append(suites.Suites, newSuite(skipped.Package, timestamp, []TestCase{testCase}))
+	}
+	if opts.ByPackage {
+		suites.Suites = append(suites.Suites, newSuite("dreamlint", timestamp, packageCases))
+	}
+
+	for _, suite := range suites.Suites {
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+	}
+	return suites
+}
+
+// newSuite returns a test suite of cases with their counts.
+func newSuite(name, timestamp string, cases []TestCase) TestSuite {
+	suite := TestSuite{Name: name, Timestamp: timestamp, Cases: cases, Tests: len(cases)}
+	for _, testCase := range cases {
+		switch {
+		case len(testCase.Failures) > 0:
+			suite.Failures++
+		case testCase.Skipped != nil:
+			suite.Skipped++
+		}
+	}
+	return suite
+}
+
+// addIssues adds the failing issues to testCase as failures, and the other
+// issues to its output.
+func addIssues(testCase *TestCase, issues []report.Issue, failing func(report.Issue) bool) {
+	var out strings.Builder
+	for _, issue := range issues {
+		location := fmt.Sprintf("%s:%d", issue.Position.Filename, issue.Position.Line)
+		if !failing(issue) {
+			fmt.Fprintf(&out, "%s: [%s] %s: %s\n", location, issue.Severity, issue.Category, issue.Message)
+			continue
+		}
+
+		text := location + ": " + issue.Message
+		if issue.Snippet != "" {
+			text += "\n\n    " + strings.TrimSpace(issue.Snippet)
+		}
+		if issue.Suggestion != "" {
+			text += "\n\nSuggestion: " + issue.Suggestion
+		}
+		testCase.Failures = append(testCase.Failures, Failure{
+			Message: fmt.Sprintf("[%s] %s", issue.Severity, issue.Message),
+			Type:    issue.Category,
+			Text:    text,
+		})
+	}
+	if out.Len() > 0 {
+		testCase.SystemOut = &Output{Text: out.String()}
+	}
+}
+
+// unitPackage returns the package of the functions of unit, or the package
+// part of its ID when it has no functions.
+func unitPackage(id string, unit report.UnitReport) string {
+	if len(unit.Functions) > 0 && unit.Functions[0].Package != "" {
+		return unit.Functions[0].Package
+	}
+	// The package is everything before the first dot after the last slash
+	id, _, _ = strings.Cut(id, "+")
+	slash := strings.LastIndex(id, "/") + 1
+	if dot := strings.Index(id[slash:], "."); dot >= 0 {
+		return id[:slash+dot]
+	}
+	return id
+}
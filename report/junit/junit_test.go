@@ -0,0 +1,130 @@
+package junit
+
+import (
+	"encoding/xml"
+	"go/token"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loov/dreamlint/report"
+)
+
+func testReport() *report.Report {
+	r := report.NewReport()
+	r.Metadata.CompletedAt = r.Metadata.GeneratedAt.Add(90 * time.Second)
+	r.Metadata.SkippedPackages = []report.SkippedPackage{{Package: "example.com/broken", Errors: []string{"syntax error"}}}
+	r.AddUnit("example.com/pkg.Query", report.UnitReport{
+		Functions: []report.FunctionInfo{{
+			ID:       "example.com/pkg.Query",
+			Package:  "example.com/pkg",
+			Position: token.Position{Filename: "pkg/query.go", Line: 10},
+		}},
+		Issues: []report.Issue{{
+			Position:   token.Position{Filename: "pkg/query.go", Line: 12},
+			Severity:   report.SeverityHigh,
+			Category:   "security",
+			Message:    "SQL injection",
+			Snippet:    `db.Query("SELECT " + id)`,
+			Suggestion: "use a parameter",
+		}, {
+			Position: token.Position{Filename: "pkg/query.go", Line: 14},
+			Severity: report.SeverityLow,
+			Category: "maintainability",
+			Message:  "long function",
+		}},
+	})
+	r.AddUnit("example.com/pkg.(*T).Close", report.UnitReport{
+		Functions: []report.FunctionInfo{{
+			ID:       "example.com/pkg.(*T).Close",
+			Package:  "example.com/pkg",
+			Position: token.Position{Filename: "pkg/t.go", Line: 3},
+		}},
+	})
+	r.AddUnit("example.com/other.Run", report.UnitReport{
+		Functions: []report.FunctionInfo{{ID: "example.com/other.Run", Package: "example.com/other"}},
+	})
+	return r
+}
+
+func TestFromReport(t *testing.T) {
+	suites := FromReport(testReport(), Options{MinSeverity: report.SeverityMedium})
+
+	if suites.Tests != 4 || suites.Failures != 1 || suites.Skipped != 1 || suites.Time != "90.000" {
+		t.Errorf("totals = tests %d, failures %d, skipped %d, time %s", suites.Tests, suites.Failures, suites.Skipped, suites.Time)
+	}
+	if len(suites.Suites) != 3 {
+		t.Fatalf("got %d suites, want 3", len(suites.Suites))
+	}
+	other, pkg, broken := suites.Suites[0], suites.Suites[1], suites.Suites[2]
+	if other.Name != "example.com/other" || pkg.Name != "example.com/pkg" || broken.Name != "example.com/broken" {
+		t.Errorf("suites = %s, %s, %s", other.Name, pkg.Name, broken.Name)
+	}
+	if broken.Cases[0].Skipped == nil || !strings.Contains(broken.Cases[0].Skipped.Message, "syntax error") {
+		t.Errorf("skipped package = %+v", broken.Cases[0])
+	}
+
+	if pkg.Tests != 2 || pkg.Failures != 1 {
+		t.Errorf("package suite = tests %d, failures %d", pkg.Tests, pkg.Failures)
+	}
+	closeCase, query := pkg.Cases[0], pkg.Cases[1]
+	if closeCase.Name != "(*T).Close" || len(closeCase.Failures) != 0 {
+		t.Errorf("Close = %+v", closeCase)
+	}
+	if query.Name != "Query" || query.ClassName != "example.com/pkg" || query.File != "pkg/query.go" || query.Line != 10 {
+		t.Errorf("Query = %+v", query)
+	}
+	if len(query.Failures) != 1 {
+		t.Fatalf("Query failures = %+v", query.Failures)
+	}
+	failure := query.Failures[0]
+	if failure.Message != "[high] SQL injection" || failure.Type != "security" {
+		t.Errorf("failure = %+v", failure)
+	}
+	for _, want := range []string{"pkg/query.go:12: SQL injection", `db.Query("SELECT " + id)`, "Suggestion: use a parameter"} {
+		if !strings.Contains(failure.Text, want) {
+			t.Errorf("failure text does not contain %q:\n%s", want, failure.Text)
+		}
+	}
+	// The low severity issue is not a failure
+	if query.SystemOut == nil || !strings.Contains(query.SystemOut.Text, "pkg/query.go:14: [low] maintainability: long function") {
+		t.Errorf("system-out = %+v", query.SystemOut)
+	}
+}
+
+func TestFromReport_ByPackage(t *testing.T) {
+	suites := FromReport(testReport(), Options{ByPackage: true})
+
+	if len(suites.Suites) != 1 {
+		t.Fatalf("got %d suites, want 1", len(suites.Suites))
+	}
+	suite := suites.Suites[0]
+	if suite.Tests != 3 || suite.Failures != 1 || suite.Skipped != 1 {
+		t.Errorf("suite = tests %d, failures %d, skipped %d", suite.Tests, suite.Failures, suite.Skipped)
+	}
+	pkg := suite.Cases[1]
+	if pkg.Name != "example.com/pkg" || len(pkg.Failures) != 2 {
+		t.Errorf("package case = %+v", pkg)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	data, err := Write(testReport(), Options{})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if !strings.HasPrefix(string(data), xml.Header) {
+		t.Error("missing XML header")
+	}
+
+	var parsed TestSuites
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if parsed.Failures != 1 || len(parsed.Suites) != 3 || len(parsed.Suites[1].Cases[1].Failures) != 2 {
+		t.Errorf("parsed = %+v", parsed)
+	}
+	if strings.Contains(string(data), "<skipped></skipped>") || strings.Contains(string(data), "<system-out></system-out>") {
+		t.Errorf("empty elements in output:\n%s", data)
+	}
+}
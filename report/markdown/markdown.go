@@ -4,6 +4,7 @@ package markdown
 import (
 	"fmt"
 	"os"
+	"slices"
 	"sort"
 	"strings"
 
@@ -25,29 +26,43 @@ func Write(r *report.Report) string {
 	b.WriteString("| Severity | Count |\n")
 	b.WriteString("|----------|-------|\n")
 
-	for _, sev := range []string{"critical", "high", "medium", "low", "info"} {
-		if count, ok := r.Summary.BySeverity[sev]; ok && count > 0 {
+	severities := r.Severities()
+	names := make([]string, 0, len(r.Summary.BySeverity))
+	for sev := range r.Summary.BySeverity {
+		names = append(names, sev)
+	}
+	severities.Sort(names)
+	for _, sev := range names {
+		if count := r.Summary.BySeverity[sev]; count > 0 {
 			b.WriteString(fmt.Sprintf("| %s | %d |\n", titleCase(sev), count))
 		}
 	}
 	b.WriteString("\n")
+	if r.Summary.Suppressed > 0 {
+		b.WriteString(fmt.Sprintf("%d issue(s) suppressed by triage decisions.\n\n", r.Summary.Suppressed))
+	}
 
-	// Critical issues first
-	if len(r.Summary.CriticalUnits) > 0 {
-		b.WriteString("## Critical Issues\n\n")
-		for _, unitID := range r.Summary.CriticalUnits {
-			unit := r.Units[unitID]
-			writeUnitIssues(&b, unitID, unit, report.SeverityCritical)
+	// Packages left out because of errors
+	if len(r.Metadata.SkippedPackages) > 0 {
+		b.WriteString("## Skipped Packages\n\n")
+		for _, skipped := range r.Metadata.SkippedPackages {
+			b.WriteString(fmt.Sprintf("- `%s`: %s\n", skipped.Package, strings.Join(skipped.Errors, "; ")))
 		}
+		b.WriteString("\n")
 	}
 
-	// High issues
-	highUnits := findUnitsWithSeverity(r, report.SeverityHigh)
-	if len(highUnits) > 0 {
-		b.WriteString("## High Priority Issues\n\n")
-		for _, unitID := range highUnits {
-			unit := r.Units[unitID]
-			writeUnitIssues(&b, unitID, unit, report.SeverityHigh)
+	// Issues reported as SARIF errors first, most severe first
+	for _, level := range severities {
+		if level.SARIF != report.SARIFError {
+			continue
+		}
+		units := findUnitsWithSeverity(r, level.Name)
+		if len(units) == 0 {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("## %s Issues\n\n", titleCase(string(level.Name))))
+		for _, unitID := range units {
+			writeUnitIssues(&b, unitID, r.Units[unitID], level.Name)
 		}
 	}
 
@@ -63,7 +78,7 @@ func Write(r *report.Report) string {
 
 	for _, unitID := range unitIDs {
 		unit := r.Units[unitID]
-		writeUnitSummary(&b, unitID, unit)
+		writeUnitSummary(&b, unitID, unit, severities)
 	}
 
 	return b.String()
@@ -102,7 +117,7 @@ func writeUnitIssues(b *strings.Builder, unitID string, unit report.UnitReport,
 	b.WriteString("---\n\n")
 }
 
-func writeUnitSummary(b *strings.Builder, unitID string, unit report.UnitReport) {
+func writeUnitSummary(b *strings.Builder, unitID string, unit report.UnitReport, severities report.Severities) {
 	b.WriteString(fmt.Sprintf("### %s\n", unitID))
 
 	if unit.Summary.Purpose != "" {
@@ -115,7 +130,11 @@ func writeUnitSummary(b *strings.Builder, unitID string, unit report.UnitReport)
 
 	if len(unit.Issues) > 0 {
 		b.WriteString("Issues:\n")
-		for _, issue := range unit.Issues {
+		issues := slices.Clone(unit.Issues)
+		slices.SortStableFunc(issues, func(a, b report.Issue) int {
+			return severities.Compare(a.Severity, b.Severity)
+		})
+		for _, issue := range issues {
 			b.WriteString(fmt.Sprintf("- [%s] %s\n", issue.Severity, issue.Message))
 		}
 		b.WriteString("\n")
@@ -141,3 +160,77 @@ func WriteFile(r *report.Report, path string) error {
 	md := Write(r)
 	return os.WriteFile(path, []byte(md), 0644)
 }
+
+// WriteDiff renders the difference between two reports as markdown
+func WriteDiff(d *report.Diff, severities report.Severities) string {
+	var b strings.Builder
+
+	b.WriteString("# Report Diff\n\n")
+	b.WriteString("| Change | Count |\n")
+	b.WriteString("|--------|-------|\n")
+	b.WriteString(fmt.Sprintf("| Added | %d |\n", d.Summary.Added))
+	b.WriteString(fmt.Sprintf("| Removed | %d |\n", d.Summary.Removed))
+	b.WriteString(fmt.Sprintf("| Severity changed | %d |\n", d.Summary.Severity))
+	b.WriteString(fmt.Sprintf("| Unchanged | %d |\n", d.Summary.Unchanged))
+	b.WriteString(fmt.Sprintf("| Summaries changed | %d |\n", d.Summary.Summaries))
+	b.WriteString("\n")
+
+	for _, unit := range d.Units {
+		switch unit.Status {
+		case report.UnitAdded:
+			b.WriteString(fmt.Sprintf("## %s (new unit)\n\n", unit.Unit))
+		case report.UnitRemoved:
+			b.WriteString(fmt.Sprintf("## %s (removed unit)\n\n", unit.Unit))
+		default:
+			b.WriteString(fmt.Sprintf("## %s\n\n", unit.Unit))
+		}
+
+		for _, pass := range unit.Passes {
+			b.WriteString(fmt.Sprintf("### %s\n\n", pass.Pass))
+			for _, issue := range pass.Added {
+				b.WriteString(fmt.Sprintf("- **Added** [%s] `%s` %s\n", issue.Severity, issuePosition(issue), issue.Message))
+			}
+			for _, issue := range pass.Removed {
+				b.WriteString(fmt.Sprintf("- **Removed** [%s] `%s` %s\n", issue.Severity, issuePosition(issue), issue.Message))
+			}
+			for _, change := range pass.Severity {
+				direction := "Raised"
+				if severities.Compare(change.New.Severity, change.Old.Severity) > 0 {
+					direction = "Lowered"
+				}
+				b.WriteString(fmt.Sprintf("- **%s** [%s → %s] `%s` %s\n", direction,
+					change.Old.Severity, change.New.Severity, issuePosition(change.New), change.New.Message))
+			}
+			b.WriteString("\n")
+		}
+
+		if unit.Summary != nil {
+			old, newer := unit.Summary.Old, unit.Summary.New
+			b.WriteString("### Summary\n\n")
+			b.WriteString("| | Old | New |\n")
+			b.WriteString("|-|-----|-----|\n")
+			row := func(name, old, newer string) {
+				if old != newer {
+					b.WriteString(fmt.Sprintf("| %s | %s | %s |\n", name, tableCell(old), tableCell(newer)))
+				}
+			}
+			row("Purpose", old.Purpose, newer.Purpose)
+			row("Behavior", old.Behavior, newer.Behavior)
+			row("Invariants", strings.Join(old.Invariants, "; "), strings.Join(newer.Invariants, "; "))
+			row("Security", strings.Join(old.Security, "; "), strings.Join(newer.Security, "; "))
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// issuePosition returns the file:line of issue.
+func issuePosition(issue report.Issue) string {
+	return fmt.Sprintf("%s:%d", issue.Position.Filename, issue.Position.Line)
+}
+
+// tableCell escapes s for use in a table cell.
+func tableCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -52,3 +52,58 @@ func TestWrite(t *testing.T) {
 		t.Error("missing function purpose")
 	}
 }
+
+func TestWrite_Severities(t *testing.T) {
+	r := report.NewReport()
+	r.Metadata.Severities = report.Severities{
+		{Name: "blocker", SARIF: report.SARIFError},
+		{Name: "nit", SARIF: report.SARIFNote},
+	}
+	r.AddIssue("pkg.A", report.Issue{Severity: "nit", Category: "style", Message: "naming"})
+	r.AddIssue("pkg.B", report.Issue{Severity: "blocker", Category: "correctness", Message: "nil dereference"})
+
+	md := Write(r)
+
+	if !strings.Contains(md, "## Blocker Issues") {
+		t.Errorf("missing section for blocker issues:\n%s", md)
+	}
+	if strings.Contains(md, "## Nit Issues") {
+		t.Errorf("notes should not get their own section:\n%s", md)
+	}
+	if i, k := strings.Index(md, "| Blocker |"), strings.Index(md, "| Nit |"); i < 0 || k < 0 || i > k {
+		t.Errorf("summary table not ordered by severity:\n%s", md)
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	old := report.NewReport()
+	old.AddUnit("testpkg.Hello", report.UnitReport{
+		Summary: report.FunctionSummary{Purpose: "Returns a greeting"},
+		Issues: []report.Issue{
+			{Position: token.Position{Filename: "main.go", Line: 12}, Severity: report.SeverityLow, Category: "security", Message: "unchecked input", Snippet: "return name"},
+			{Position: token.Position{Filename: "main.go", Line: 13}, Severity: report.SeverityLow, Category: "correctness", Message: "old issue", Snippet: "x++"},
+		},
+	})
+	newer := report.NewReport()
+	newer.AddUnit("testpkg.Hello", report.UnitReport{
+		Summary: report.FunctionSummary{Purpose: "Returns a | greeting"},
+		Issues: []report.Issue{
+			{Position: token.Position{Filename: "main.go", Line: 12}, Severity: report.SeverityHigh, Category: "security", Message: "unchecked input", Snippet: "return name"},
+		},
+	})
+
+	md := WriteDiff(report.Compare(old, newer), report.DefaultSeverities)
+	for _, want := range []string{
+		"# Report Diff",
+		"| Removed | 1 |",
+		"## testpkg.Hello",
+		"### security",
+		"- **Raised** [low → high] `main.go:12` unchecked input",
+		"- **Removed** [low] `main.go:13` old issue",
+		`| Purpose | Returns a greeting | Returns a \| greeting |`,
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("missing %q in:\n%s", want, md)
+		}
+	}
+}
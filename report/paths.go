@@ -0,0 +1,72 @@
+package report
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Paths makes the file paths of reports independent of the machine
+type Paths struct {
+	// Root is the absolute directory that paths are made relative to, usually the module root.
+	Root string
+	// Prefix is prepended to relative paths, e.g. "services/api" when
+	// the module is in a subdirectory of the repository.
+	Prefix string
+}
+
+// Rel returns filename relative to Root with Prefix prepended, using forward slashes.
+// Files outside of Root are returned unchanged.
+func (p Paths) Rel(filename string) string {
+	if p.Root == "" || !filepath.IsAbs(filename) {
+		return filename
+	}
+	rel, err := filepath.Rel(p.Root, filename)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filename
+	}
+	return path.Join(filepath.ToSlash(p.Prefix), filepath.ToSlash(rel))
+}
+
+// SourceRoot returns the absolute directory that the relative paths are relative to,
+// which is Root without Prefix. It returns "" when Root does not end with Prefix.
+func (p Paths) SourceRoot() string {
+	prefix := strings.Trim(filepath.ToSlash(p.Prefix), "/")
+	if p.Root == "" || prefix == "" {
+		return p.Root
+	}
+	root := filepath.ToSlash(p.Root)
+	if !strings.HasSuffix(root, "/"+prefix) {
+		return ""
+	}
+	return filepath.FromSlash(strings.TrimSuffix(root, "/"+prefix))
+}
+
+// Unit makes the positions of unit relative.
+func (p Paths) Unit(unit *UnitReport) {
+	for i := range unit.Functions {
+		unit.Functions[i].Position.Filename = p.Rel(unit.Functions[i].Position.Filename)
+	}
+	for i := range unit.Issues {
+		issue := &unit.Issues[i]
+		filename := issue.Position.Filename
+		issue.Position.Filename = p.Rel(filename)
+		if issue.Fix != nil {
+			issue.Fix.Diff = renameDiff(issue.Fix.Diff, filename, issue.Position.Filename)
+		}
+	}
+}
+
+// renameDiff replaces the file name in the header of a unified diff.
+func renameDiff(diff, from, to string) string {
+	if from == to {
+		return diff
+	}
+	lines := strings.SplitAfterN(diff, "\n", 4)
+	for i, line := range lines[:min(3, len(lines))] {
+		if strings.HasPrefix(line, "diff ") || strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ ") {
+			lines[i] = strings.ReplaceAll(line, from, to)
+		}
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,61 @@
+package report
+
+import (
+	"go/token"
+	"path/filepath"
+	"testing"
+)
+
+func TestPaths(t *testing.T) {
+	root := filepath.FromSlash("/src/repo/services/api")
+
+	tests := []struct {
+		paths    Paths
+		filename string
+		want     string
+	}{
+		{Paths{Root: root}, filepath.Join(root, "handler", "h.go"), "handler/h.go"},
+		{Paths{Root: root, Prefix: "services/api"}, filepath.Join(root, "h.go"), "services/api/h.go"},
+		{Paths{Root: root, Prefix: "services/api/"}, filepath.Join(root, "h.go"), "services/api/h.go"},
+		{Paths{Root: root}, filepath.FromSlash("/src/repo/other/o.go"), filepath.FromSlash("/src/repo/other/o.go")},
+		{Paths{Root: root}, "relative.go", "relative.go"},
+		{Paths{}, filepath.Join(root, "h.go"), filepath.Join(root, "h.go")},
+	}
+	for _, test := range tests {
+		if got := test.paths.Rel(test.filename); got != test.want {
+			t.Errorf("%+v.Rel(%q) = %q, want %q", test.paths, test.filename, got, test.want)
+		}
+	}
+
+	sourceRoots := []struct {
+		paths Paths
+		want  string
+	}{
+		{Paths{Root: root}, root},
+		{Paths{Root: root, Prefix: "services/api"}, filepath.FromSlash("/src/repo")},
+		{Paths{Root: root, Prefix: "other"}, ""},
+		{Paths{}, ""},
+	}
+	for _, test := range sourceRoots {
+		if got := test.paths.SourceRoot(); got != test.want {
+			t.Errorf("%+v.SourceRoot() = %q, want %q", test.paths, got, test.want)
+		}
+	}
+}
+
+func TestPaths_UnitFix(t *testing.T) {
+	root := filepath.FromSlash("/src/repo")
+	filename := filepath.Join(root, "pkg", "a.go")
+	diff := "--- " + filename + "\n+++ " + filename + "\n@@ -1,1 +1,1 @@\n-a " + filename + "\n+b\n"
+
+	unit := UnitReport{Issues: []Issue{{
+		Position: token.Position{Filename: filename, Line: 1},
+		Fix:      &Fix{Diff: diff},
+	}}}
+	Paths{Root: root}.Unit(&unit)
+
+	want := "--- pkg/a.go\n+++ pkg/a.go\n@@ -1,1 +1,1 @@\n-a " + filename + "\n+b\n"
+	if got := unit.Issues[0].Fix.Diff; got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
@@ -1,30 +1,14 @@
 package report
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"go/token"
+	"slices"
+	"strings"
 	"time"
 )
 
-// Severity levels
-type Severity string
-
-const (
-	SeverityCritical Severity = "critical"
-	SeverityHigh     Severity = "high"
-	SeverityMedium   Severity = "medium"
-	SeverityLow      Severity = "low"
-	SeverityInfo     Severity = "info"
-)
-
-// SeverityStrings is a list of all severity levels
-var SeverityStrings = []string{
-	string(SeverityCritical),
-	string(SeverityHigh),
-	string(SeverityMedium),
-	string(SeverityLow),
-	string(SeverityInfo),
-}
-
 // Report is the complete analysis report
 type Report struct {
 	Metadata Metadata              `json:"metadata"`
@@ -40,22 +24,55 @@ type Metadata struct {
 	InlineConfigs []string  `json:"inline_configs,omitempty"`
 	TotalUnits    int       `json:"total_units"`
 	CacheHits     int       `json:"cache_hits"`
+
+	// CompletedAt is set when the analysis of all units finished.
+	CompletedAt time.Time `json:"completed_at,omitzero"`
+	// Root is the absolute directory that relative file paths are relative to,
+	// usually the module or repository root.
+	Root string `json:"root,omitempty"`
+	// Passes describes the analysis passes, the issue categories.
+	Passes []PassInfo `json:"passes,omitempty"`
+
+	// Severities is the severity taxonomy of the issues, empty means DefaultSeverities.
+	Severities Severities `json:"severities,omitempty"`
+
+	SkippedPackages []SkippedPackage `json:"skipped_packages,omitempty"`
+}
+
+// PassInfo describes an analysis pass
+type PassInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// SkippedPackage describes a package left out of the analysis
+type SkippedPackage struct {
+	Package string   `json:"package"`
+	Errors  []string `json:"errors"`
 }
 
 // UnitReport holds analysis results for a single unit
 type UnitReport struct {
 	Functions []FunctionInfo  `json:"functions"`
+	Passes    []string        `json:"passes,omitempty"` // passes run after applying overrides
 	Summary   FunctionSummary `json:"summary"`
 	Issues    []Issue         `json:"issues"`
 }
 
 // FunctionInfo holds function metadata
 type FunctionInfo struct {
+	// ID identifies the function, e.g. "example.com/pkg.(*Type).Method".
+	// The format is described by extract.FuncID.
+	ID        string         `json:"id"`
 	Package   string         `json:"package"`
 	Name      string         `json:"name"`
 	Receiver  string         `json:"receiver,omitempty"`
 	Signature string         `json:"signature"`
 	Position  token.Position `json:"position"`
+	EndLine   int            `json:"end_line,omitempty"`
+	// FileHash is the hash of the file content at the time of the analysis,
+	// used to detect whether the file changed since.
+	FileHash string `json:"file_hash,omitempty"`
 }
 
 // FunctionSummary describes function behavior
@@ -69,19 +86,59 @@ type FunctionSummary struct {
 // Issue represents a found problem
 type Issue struct {
 	Position   token.Position `json:"position"`
+	EndLine    int            `json:"end_line,omitempty"`
+	Function   string         `json:"function,omitempty"` // ID of the function containing the issue
 	Severity   Severity       `json:"severity"`
 	Category   string         `json:"category"`
 	Message    string         `json:"message"`
 	Snippet    string         `json:"snippet,omitempty"`
 	Suggestion string         `json:"suggestion,omitempty"`
+	// Confidence is the confidence of the LLM that the issue is real, from 0 to 1.
+	Confidence float64 `json:"confidence,omitempty"`
+	Model      string  `json:"model,omitempty"`
+	Fix        *Fix    `json:"fix,omitempty"`
+	// Triage is the decision of an earlier triage of the issue, e.g. "true_positive".
+	Triage string `json:"triage,omitempty"`
+}
+
+// Fingerprint identifies the issue of a unit independently of its line
+// number, so that it stays the same when unrelated code moves.
+func (issue Issue) Fingerprint(unitID string) string {
+	function := issue.Function
+	if function == "" {
+		function = unitID
+	}
+	code := strings.Join(strings.Fields(issue.Snippet), " ")
+	if code == "" {
+		code = issue.Message
+	}
+	sum := sha256.Sum256([]byte(issue.Category + "\x00" + function + "\x00" + code))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Fix is a machine generated change of the function containing an issue
+type Fix struct {
+	// Diff is the change as a unified diff of the file.
+	Diff string `json:"diff"`
+	// StartLine, StartColumn, EndLine and EndColumn delimit the replaced
+	// source, where EndColumn is the column after the last replaced character.
+	StartLine   int `json:"start_line"`
+	StartColumn int `json:"start_column"`
+	EndLine     int `json:"end_line"`
+	EndColumn   int `json:"end_column"`
+	// Replacement is the new source.
+	Replacement string `json:"replacement"`
 }
 
 // Summary aggregates issue counts
 type Summary struct {
-	TotalIssues   int            `json:"total_issues"`
-	BySeverity    map[string]int `json:"by_severity"`
-	ByCategory    map[string]int `json:"by_category"`
-	CriticalUnits []string       `json:"critical_units"`
+	TotalIssues int            `json:"total_issues"`
+	BySeverity  map[string]int `json:"by_severity"`
+	ByCategory  map[string]int `json:"by_category"`
+	// CriticalUnits lists units with issues of the most severe level.
+	CriticalUnits []string `json:"critical_units"`
+	// Suppressed counts the issues left out because of triage decisions.
+	Suppressed int `json:"suppressed,omitempty"`
 }
 
 // NewReport creates a new empty report
@@ -98,26 +155,36 @@ func NewReport() *Report {
 	}
 }
 
+// Severities returns the severity taxonomy of the report.
+func (r *Report) Severities() Severities {
+	if len(r.Metadata.Severities) > 0 {
+		return r.Metadata.Severities
+	}
+	return DefaultSeverities
+}
+
+// AddUnit adds the report of a unit and counts its issues in the summary.
+func (r *Report) AddUnit(unitID string, unit UnitReport) {
+	r.Units[unitID] = unit
+	for _, issue := range unit.Issues {
+		r.countIssue(unitID, issue)
+	}
+}
+
 // AddIssue adds an issue to a unit and updates summary
 func (r *Report) AddIssue(unitID string, issue Issue) {
 	unit := r.Units[unitID]
 	unit.Issues = append(unit.Issues, issue)
 	r.Units[unitID] = unit
+	r.countIssue(unitID, issue)
+}
 
+func (r *Report) countIssue(unitID string, issue Issue) {
 	r.Summary.TotalIssues++
 	r.Summary.BySeverity[string(issue.Severity)]++
 	r.Summary.ByCategory[issue.Category]++
 
-	if issue.Severity == SeverityCritical {
-		found := false
-		for _, u := range r.Summary.CriticalUnits {
-			if u == unitID {
-				found = true
-				break
-			}
-		}
-		if !found {
-			r.Summary.CriticalUnits = append(r.Summary.CriticalUnits, unitID)
-		}
+	if r.Severities().Rank(issue.Severity) == 0 && !slices.Contains(r.Summary.CriticalUnits, unitID) {
+		r.Summary.CriticalUnits = append(r.Summary.CriticalUnits, unitID)
 	}
 }
@@ -2,11 +2,23 @@ package sarif
 
 import (
 	"encoding/json"
+	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/loov/dreamlint/report"
 )
 
+// SourceRoot is the uriBaseId of paths relative to report.Metadata.Root.
+const SourceRoot = "SRCROOT"
+
+// fingerprintKey identifies the version of the fingerprint algorithm.
+const fingerprintKey = "dreamlint/v1"
+
 // Write serializes the report to SARIF JSON
 func Write(r *report.Report) ([]byte, error) {
 	s := FromReport(r)
@@ -24,7 +36,18 @@ func WriteFile(r *report.Report, path string) error {
 
 // FromReport converts a Report to SARIF format
 func FromReport(r *report.Report) *Report {
+	// Sort unit IDs for deterministic output
+	unitIDs := make([]string, 0, len(r.Units))
+	for id := range r.Units {
+		unitIDs = append(unitIDs, id)
+	}
+	sort.Strings(unitIDs)
+
 	// Collect unique categories as rules
+	descriptions := make(map[string]string)
+	for _, pass := range r.Metadata.Passes {
+		descriptions[pass.Name] = pass.Description
+	}
 	categories := make(map[string]bool)
 	for _, unit := range r.Units {
 		for _, issue := range unit.Issues {
@@ -34,73 +57,216 @@ func FromReport(r *report.Report) *Report {
 
 	rules := make([]Rule, 0, len(categories))
 	for cat := range categories {
-		rules = append(rules, Rule{
+		rule := Rule{
 			ID:               cat,
 			Name:             cat,
 			ShortDescription: Message{Text: cat + " analysis"},
-		})
+		}
+		if description := descriptions[cat]; description != "" {
+			rule.ShortDescription = Message{Text: firstSentence(description)}
+			rule.FullDescription = &Message{Text: description}
+		}
+		rules = append(rules, rule)
 	}
+	sort.Slice(rules, func(i, k int) bool { return rules[i].ID < rules[k].ID })
 
 	// Convert issues to results
+	severities := r.Severities()
+	occurrences := make(map[string]int)
 	var results []Result
-	for _, unit := range r.Units {
+	for _, unitID := range unitIDs {
+		unit := r.Units[unitID]
 		for _, issue := range unit.Issues {
 			result := Result{
 				RuleID:  issue.Category,
-				Level:   severityToLevel(issue.Severity),
+				Level:   severities.SARIFLevel(issue.Severity),
 				Message: Message{Text: issue.Message},
+				Properties: &Properties{
+					Severity:   string(issue.Severity),
+					Pass:       issue.Category,
+					Model:      issue.Model,
+					Confidence: issue.Confidence,
+					Function:   issue.Function,
+					Suggestion: issue.Suggestion,
+					Triage:     issue.Triage,
+				},
+			}
+			if issue.Suggestion != "" {
+				result.Message.Text += "\n\nSuggestion: " + issue.Suggestion
+			}
+
+			if issue.Position.Filename != "" && issue.Fix != nil {
+				result.Fixes = []Fix{fixOf(r.Metadata.Root, issue)}
 			}
 
 			if issue.Position.Filename != "" {
+				region := &Region{
+					StartLine:   issue.Position.Line,
+					StartColumn: issue.Position.Column,
+					EndLine:     issue.EndLine,
+				}
+				if issue.Snippet != "" {
+					region.Snippet = &ArtifactContent{Text: issue.Snippet}
+				}
 				result.Locations = []Location{{
 					PhysicalLocation: PhysicalLocation{
-						ArtifactLocation: ArtifactLocation{
-							URI: issue.Position.Filename,
-						},
-						Region: &Region{
-							StartLine:   issue.Position.Line,
-							StartColumn: issue.Position.Column,
-						},
+						ArtifactLocation: artifactLocation(r.Metadata.Root, issue.Position.Filename),
+						Region:           region,
+						ContextRegion:    functionRegion(unit, issue),
 					},
 				}}
+				if flow := codeFlow(r.Metadata.Root, unit, issue, result.Locations[0]); flow != nil {
+					result.CodeFlows = []CodeFlow{*flow}
+				}
 			}
 
-			if issue.Suggestion != "" {
-				result.Fixes = []Fix{{
-					Description: Message{Text: issue.Suggestion},
-				}}
+			// Identical issues in the same function are told apart by their occurrence
+			fingerprint := issue.Fingerprint(unitID)
+			occurrences[fingerprint]++
+			result.PartialFingerprints = map[string]string{
+				fingerprintKey: fmt.Sprintf("%s:%d", fingerprint, occurrences[fingerprint]),
 			}
 
 			results = append(results, result)
 		}
 	}
 
+	run := Run{
+		Tool: Tool{
+			Driver: Driver{
+				Name:    "dreamlint",
+				Version: "0.1.0",
+				Rules:   rules,
+			},
+		},
+		Invocations: []Invocation{invocation(r)},
+		Results:     results,
+	}
+	if r.Metadata.Root != "" {
+		run.OriginalURIBaseIDs = map[string]ArtifactLocation{
+			SourceRoot: {URI: directoryURI(r.Metadata.Root)},
+		}
+	}
+
 	return &Report{
 		Schema:  "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
 		Version: "2.1.0",
-		Runs: []Run{{
-			Tool: Tool{
-				Driver: Driver{
-					Name:    "dreamlint",
-					Version: "0.1.0",
-					Rules:   rules,
+		Runs:    []Run{run},
+	}
+}
+
+// fixOf converts the fix of issue.
+func fixOf(root string, issue report.Issue) Fix {
+	fix := Fix{
+		ArtifactChanges: []ArtifactChange{{
+			ArtifactLocation: artifactLocation(root, issue.Position.Filename),
+			Replacements: []Replacement{{
+				DeletedRegion: Region{
+					StartLine:   issue.Fix.StartLine,
+					StartColumn: issue.Fix.StartColumn,
+					EndLine:     issue.Fix.EndLine,
+					EndColumn:   issue.Fix.EndColumn,
 				},
-			},
-			Results: results,
+				InsertedContent: &ArtifactContent{Text: issue.Fix.Replacement},
+			}},
 		}},
 	}
+	if issue.Suggestion != "" {
+		fix.Description = &Message{Text: issue.Suggestion}
+	}
+	return fix
+}
+
+// invocation describes the analysis run, which was successful when it completed.
+func invocation(r *report.Report) Invocation {
+	inv := Invocation{
+		ExecutionSuccessful: !r.Metadata.CompletedAt.IsZero(),
+	}
+	if !r.Metadata.GeneratedAt.IsZero() {
+		inv.StartTimeUTC = r.Metadata.GeneratedAt.UTC().Format(time.RFC3339)
+	}
+	if !r.Metadata.CompletedAt.IsZero() {
+		inv.EndTimeUTC = r.Metadata.CompletedAt.UTC().Format(time.RFC3339)
+	}
+	for _, skipped := range r.Metadata.SkippedPackages {
+		inv.ToolExecutionNotifications = append(inv.ToolExecutionNotifications, Notification{
+			Level:   "warning",
+			Message: Message{Text: fmt.Sprintf("skipped package %s: %s", skipped.Package, strings.Join(skipped.Errors, "; "))},
+		})
+	}
+	return inv
+}
+
+// artifactLocation returns the location of filename. Relative paths and
+// absolute paths inside root are relative to SourceRoot.
+func artifactLocation(root, filename string) ArtifactLocation {
+	if !filepath.IsAbs(filename) {
+		return ArtifactLocation{URI: filepath.ToSlash(filename), URIBaseID: SourceRoot}
+	}
+	if root != "" {
+		if rel, err := filepath.Rel(root, filename); err == nil && !strings.HasPrefix(rel, "..") {
+			return ArtifactLocation{URI: filepath.ToSlash(rel), URIBaseID: SourceRoot}
+		}
+	}
+	return ArtifactLocation{URI: fileURI(filename)}
+}
+
+// directoryURI returns the file URI of dir, ending with a slash as required for base URIs.
+func directoryURI(dir string) string {
+	uri := fileURI(dir)
+	if !strings.HasSuffix(uri, "/") {
+		uri += "/"
+	}
+	return uri
+}
+
+// fileURI returns the file URI of an absolute path.
+func fileURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		// Windows paths such as C:/src
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// functionRegion returns the lines of the function containing issue.
+func functionRegion(unit report.UnitReport, issue report.Issue) *Region {
+	for _, fn := range unit.Functions {
+		if fn.ID != issue.Function || fn.EndLine == 0 {
+			continue
+		}
+		return &Region{StartLine: fn.Position.Line, EndLine: fn.EndLine}
+	}
+	return nil
+}
+
+// codeFlow returns the flow from the start of the function containing issue
+// to the issue at location, or nil when the function is not in unit.
+func codeFlow(root string, unit report.UnitReport, issue report.Issue, location Location) *CodeFlow {
+	for _, fn := range unit.Functions {
+		if fn.ID != issue.Function || fn.Position.Filename == "" {
+			continue
+		}
+		start := Location{
+			PhysicalLocation: PhysicalLocation{
+				ArtifactLocation: artifactLocation(root, fn.Position.Filename),
+				Region:           &Region{StartLine: fn.Position.Line, StartColumn: fn.Position.Column},
+			},
+			Message: &Message{Text: "in " + fn.ID},
+		}
+		location.Message = &Message{Text: issue.Message}
+		return &CodeFlow{ThreadFlows: []ThreadFlow{{
+			Locations: []ThreadFlowLocation{{Location: start}, {Location: location}},
+		}}}
+	}
+	return nil
 }
 
-// severityToLevel converts our severity to SARIF level
-func severityToLevel(s report.Severity) string {
-	switch s {
-	case report.SeverityCritical:
-		return "error"
-	case "important":
-		return "warning"
-	case "minor":
-		return "note"
-	default:
-		return "note"
+// firstSentence returns the first sentence of text.
+func firstSentence(text string) string {
+	if i := strings.Index(text, ". "); i >= 0 {
+		return text[:i+1]
 	}
+	return text
 }
@@ -0,0 +1,169 @@
+package sarif
+
+import (
+	"go/token"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/loov/dreamlint/report"
+)
+
+func testReport(root string) *report.Report {
+	r := report.NewReport()
+	r.Metadata.Root = root
+	r.Metadata.CompletedAt = r.Metadata.GeneratedAt.Add(time.Minute)
+	r.Metadata.Passes = []report.PassInfo{{Name: "security", Description: "Finds vulnerabilities. Checks injection and secrets."}}
+	r.AddUnit("example.com/pkg.Query", report.UnitReport{
+		Functions: []report.FunctionInfo{{
+			ID:       "example.com/pkg.Query",
+			Position: token.Position{Filename: filepath.Join(root, "pkg", "query.go"), Line: 10},
+			EndLine:  20,
+		}},
+		Issues: []report.Issue{{
+			Position:   token.Position{Filename: filepath.Join(root, "pkg", "query.go"), Line: 12},
+			EndLine:    12,
+			Function:   "example.com/pkg.Query",
+			Severity:   report.SeverityHigh,
+			Category:   "security",
+			Message:    "SQL injection",
+			Snippet:    `db.Query("SELECT " + id)`,
+			Suggestion: "use a parameter",
+			Confidence: 0.8,
+			Model:      "m",
+		}},
+	})
+	return r
+}
+
+func TestFromReport(t *testing.T) {
+	root := t.TempDir()
+	s := FromReport(testReport(root))
+
+	run := s.Runs[0]
+	if len(run.Tool.Driver.Rules) != 1 {
+		t.Fatalf("got %d rules, want 1", len(run.Tool.Driver.Rules))
+	}
+	rule := run.Tool.Driver.Rules[0]
+	if rule.ShortDescription.Text != "Finds vulnerabilities." || rule.FullDescription == nil {
+		t.Errorf("rule descriptions = %+v, %+v", rule.ShortDescription, rule.FullDescription)
+	}
+
+	if len(run.Invocations) != 1 || !run.Invocations[0].ExecutionSuccessful || run.Invocations[0].EndTimeUTC == "" {
+		t.Errorf("invocations = %+v", run.Invocations)
+	}
+	if base := run.OriginalURIBaseIDs[SourceRoot].URI; base != directoryURI(root) || base[len(base)-1] != '/' {
+		t.Errorf("SRCROOT = %q", base)
+	}
+
+	result := run.Results[0]
+	if result.Level != "error" {
+		t.Errorf("level = %s, want error", result.Level)
+	}
+	loc := result.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "pkg/query.go" || loc.ArtifactLocation.URIBaseID != SourceRoot {
+		t.Errorf("artifact location = %+v", loc.ArtifactLocation)
+	}
+	if loc.Region.EndLine != 12 || loc.Region.Snippet == nil || loc.Region.Snippet.Text != `db.Query("SELECT " + id)` {
+		t.Errorf("region = %+v", loc.Region)
+	}
+	if loc.ContextRegion == nil || loc.ContextRegion.StartLine != 10 || loc.ContextRegion.EndLine != 20 {
+		t.Errorf("context region = %+v", loc.ContextRegion)
+	}
+	if p := result.Properties; p.Pass != "security" || p.Model != "m" || p.Confidence != 0.8 {
+		t.Errorf("properties = %+v", p)
+	}
+
+	if len(result.CodeFlows) != 1 || len(result.CodeFlows[0].ThreadFlows) != 1 {
+		t.Fatalf("code flows = %+v", result.CodeFlows)
+	}
+	steps := result.CodeFlows[0].ThreadFlows[0].Locations
+	if len(steps) != 2 || steps[0].Location.PhysicalLocation.Region.StartLine != 10 ||
+		steps[1].Location.PhysicalLocation.Region.StartLine != loc.Region.StartLine {
+		t.Errorf("code flow = %+v, want the function and then the issue", steps)
+	}
+	if result.Locations[0].Message != nil {
+		t.Errorf("the result location has the message of the code flow: %+v", result.Locations[0].Message)
+	}
+}
+
+func TestFromReport_Fingerprints(t *testing.T) {
+	fingerprint := func(r *report.Report) string {
+		return FromReport(r).Runs[0].Results[0].PartialFingerprints[fingerprintKey]
+	}
+
+	r := testReport(t.TempDir())
+	before := fingerprint(r)
+
+	// Moving the code keeps the fingerprint
+	unit := r.Units["example.com/pkg.Query"]
+	unit.Issues[0].Position.Line += 5
+	unit.Issues[0].Snippet = "  db.Query(\"SELECT \"  +  id)"
+	if after := fingerprint(r); after != before {
+		t.Errorf("fingerprint changed after moving the code: %s != %s", after, before)
+	}
+
+	// Changing the code changes the fingerprint
+	unit.Issues[0].Snippet = `db.Query("DELETE " + id)`
+	if after := fingerprint(r); after == before {
+		t.Error("fingerprint did not change after changing the code")
+	}
+
+	// Duplicate issues get distinct fingerprints
+	unit.Issues = append(unit.Issues, unit.Issues[0])
+	r.Units["example.com/pkg.Query"] = unit
+	results := FromReport(r).Runs[0].Results
+	if results[0].PartialFingerprints[fingerprintKey] == results[1].PartialFingerprints[fingerprintKey] {
+		t.Error("duplicate issues have the same fingerprint")
+	}
+}
+
+func TestArtifactLocation(t *testing.T) {
+	root := filepath.FromSlash("/src/repo")
+	tests := []struct {
+		root, filename string
+		want           ArtifactLocation
+	}{
+		{"", "pkg/a.go", ArtifactLocation{URI: "pkg/a.go", URIBaseID: SourceRoot}},
+		{root, filepath.Join(root, "pkg", "a.go"), ArtifactLocation{URI: "pkg/a.go", URIBaseID: SourceRoot}},
+		{root, filepath.FromSlash("/other/a.go"), ArtifactLocation{URI: fileURI(filepath.FromSlash("/other/a.go"))}},
+	}
+	for _, test := range tests {
+		if got := artifactLocation(test.root, test.filename); got != test.want {
+			t.Errorf("artifactLocation(%q, %q) = %+v, want %+v", test.root, test.filename, got, test.want)
+		}
+	}
+}
+
+func TestFromReport_Fix(t *testing.T) {
+	root := t.TempDir()
+	r := testReport(root)
+	unit := r.Units["example.com/pkg.Query"]
+	unit.Issues[0].Fix = &report.Fix{
+		StartLine:   10,
+		StartColumn: 1,
+		EndLine:     20,
+		EndColumn:   2,
+		Replacement: "func Query(id string) {}",
+	}
+	r.Units["example.com/pkg.Query"] = unit
+
+	fixes := FromReport(r).Runs[0].Results[0].Fixes
+	if len(fixes) != 1 || len(fixes[0].ArtifactChanges) != 1 {
+		t.Fatalf("fixes = %+v", fixes)
+	}
+	if fixes[0].Description == nil || fixes[0].Description.Text != "use a parameter" {
+		t.Errorf("description = %+v", fixes[0].Description)
+	}
+	change := fixes[0].ArtifactChanges[0]
+	if change.ArtifactLocation.URI != "pkg/query.go" || len(change.Replacements) != 1 {
+		t.Fatalf("artifact change = %+v", change)
+	}
+	replacement := change.Replacements[0]
+	if region := replacement.DeletedRegion; region.StartLine != 10 || region.EndLine != 20 || region.EndColumn != 2 {
+		t.Errorf("deleted region = %+v", region)
+	}
+	if replacement.InsertedContent == nil || replacement.InsertedContent.Text != "func Query(id string) {}" {
+		t.Errorf("inserted content = %+v", replacement.InsertedContent)
+	}
+}
@@ -13,8 +13,10 @@ type Report struct {
 
 // Run represents a single analysis run
 type Run struct {
-	Tool    Tool     `json:"tool"`
-	Results []Result `json:"results"`
+	Tool               Tool                        `json:"tool"`
+	Invocations        []Invocation                `json:"invocations,omitempty"`
+	OriginalURIBaseIDs map[string]ArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Results            []Result                    `json:"results"`
 }
 
 // Tool describes the analysis tool
@@ -32,18 +34,80 @@ type Driver struct {
 
 // Rule describes a rule/category
 type Rule struct {
-	ID               string  `json:"id"`
-	Name             string  `json:"name"`
-	ShortDescription Message `json:"shortDescription"`
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	ShortDescription Message  `json:"shortDescription"`
+	FullDescription  *Message `json:"fullDescription,omitempty"`
+}
+
+// Invocation describes the execution of the tool
+type Invocation struct {
+	ExecutionSuccessful        bool           `json:"executionSuccessful"`
+	StartTimeUTC               string         `json:"startTimeUtc,omitempty"`
+	EndTimeUTC                 string         `json:"endTimeUtc,omitempty"`
+	ToolExecutionNotifications []Notification `json:"toolExecutionNotifications,omitempty"`
+}
+
+// Notification describes a condition encountered during the execution of the tool
+type Notification struct {
+	Level   string  `json:"level"`
+	Message Message `json:"message"`
 }
 
 // Result represents a single issue
 type Result struct {
-	RuleID    string     `json:"ruleId"`
-	Level     string     `json:"level"`
-	Message   Message    `json:"message"`
-	Locations []Location `json:"locations,omitempty"`
-	Fixes     []Fix      `json:"fixes,omitempty"`
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             Message           `json:"message"`
+	Locations           []Location        `json:"locations,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+	CodeFlows           []CodeFlow        `json:"codeFlows,omitempty"`
+	Fixes               []Fix             `json:"fixes,omitempty"`
+	Properties          *Properties       `json:"properties,omitempty"`
+}
+
+// CodeFlow describes the path through the code that leads to a result
+type CodeFlow struct {
+	ThreadFlows []ThreadFlow `json:"threadFlows"`
+}
+
+// ThreadFlow is a sequence of locations within a code flow
+type ThreadFlow struct {
+	Locations []ThreadFlowLocation `json:"locations"`
+}
+
+// ThreadFlowLocation is a step of a thread flow
+type ThreadFlowLocation struct {
+	Location Location `json:"location"`
+}
+
+// Fix describes a proposed fix
+type Fix struct {
+	Description     *Message         `json:"description,omitempty"`
+	ArtifactChanges []ArtifactChange `json:"artifactChanges"`
+}
+
+// ArtifactChange describes changes to a single file
+type ArtifactChange struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Replacements     []Replacement    `json:"replacements"`
+}
+
+// Replacement replaces a region of a file
+type Replacement struct {
+	DeletedRegion   Region           `json:"deletedRegion"`
+	InsertedContent *ArtifactContent `json:"insertedContent,omitempty"`
+}
+
+// Properties holds dreamlint specific details of a result
+type Properties struct {
+	Severity   string  `json:"severity"`
+	Pass       string  `json:"pass"`
+	Model      string  `json:"model,omitempty"`
+	Confidence float64 `json:"confidence,omitempty"`
+	Function   string  `json:"function,omitempty"`
+	Suggestion string  `json:"suggestion,omitempty"`
+	Triage     string  `json:"triage,omitempty"`
 }
 
 // Message holds a text message
@@ -54,26 +118,32 @@ type Message struct {
 // Location describes where an issue occurs
 type Location struct {
 	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+	Message          *Message         `json:"message,omitempty"`
 }
 
 // PhysicalLocation describes file location
 type PhysicalLocation struct {
 	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
 	Region           *Region          `json:"region,omitempty"`
+	ContextRegion    *Region          `json:"contextRegion,omitempty"`
 }
 
 // ArtifactLocation describes the file
 type ArtifactLocation struct {
-	URI string `json:"uri"`
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
 }
 
 // Region describes the position in the file
 type Region struct {
-	StartLine   int `json:"startLine"`
-	StartColumn int `json:"startColumn,omitempty"`
+	StartLine   int              `json:"startLine"`
+	StartColumn int              `json:"startColumn,omitempty"`
+	EndLine     int              `json:"endLine,omitempty"`
+	EndColumn   int              `json:"endColumn,omitempty"`
+	Snippet     *ArtifactContent `json:"snippet,omitempty"`
 }
 
-// Fix describes a suggested fix
-type Fix struct {
-	Description Message `json:"description"`
+// ArtifactContent holds a part of a file
+type ArtifactContent struct {
+	Text string `json:"text"`
 }
@@ -0,0 +1,116 @@
+package report
+
+import (
+	"slices"
+	"sort"
+)
+
+// Severity is the name of an issue severity, e.g. "critical"
+type Severity string
+
+// Severity levels of DefaultSeverities
+const (
+	SeverityCritical Severity = "critical"
+	SeverityHigh     Severity = "high"
+	SeverityMedium   Severity = "medium"
+	SeverityLow      Severity = "low"
+	SeverityInfo     Severity = "info"
+)
+
+// SARIF result levels
+const (
+	SARIFError   = "error"
+	SARIFWarning = "warning"
+	SARIFNote    = "note"
+	SARIFNone    = "none"
+)
+
+// SeverityLevel describes a severity
+type SeverityLevel struct {
+	Name        Severity `json:"name"`
+	Description string   `json:"description,omitempty"`
+	// SARIF is the SARIF result level: "error", "warning", "note" or "none".
+	SARIF string `json:"sarif"`
+}
+
+// Severities is a severity taxonomy ordered from the most to the least severe
+type Severities []SeverityLevel
+
+// DefaultSeverities is used when no severities are configured
+var DefaultSeverities = Severities{
+	{Name: SeverityCritical, SARIF: SARIFError, Description: "exploitable vulnerability, data loss or crash in normal use"},
+	{Name: SeverityHigh, SARIF: SARIFError, Description: "incorrect behavior that is likely to happen in practice"},
+	{Name: SeverityMedium, SARIF: SARIFWarning, Description: "bug in edge cases or a significant maintainability problem"},
+	{Name: SeverityLow, SARIF: SARIFNote, Description: "minor problem or robustness improvement"},
+	{Name: SeverityInfo, SARIF: SARIFNote, Description: "observation or suggestion"},
+}
+
+// Names returns the severity names from the most to the least severe.
+func (s Severities) Names() []string {
+	names := make([]string, len(s))
+	for i, level := range s {
+		names[i] = string(level.Name)
+	}
+	return names
+}
+
+// Level returns the level of sev.
+func (s Severities) Level(sev Severity) (SeverityLevel, bool) {
+	i := s.Rank(sev)
+	if i == len(s) {
+		return SeverityLevel{}, false
+	}
+	return s[i], true
+}
+
+// Rank returns the position of sev, lower is more severe.
+// Unknown severities rank below all known ones.
+func (s Severities) Rank(sev Severity) int {
+	i := slices.IndexFunc(s, func(level SeverityLevel) bool { return level.Name == sev })
+	if i < 0 {
+		return len(s)
+	}
+	return i
+}
+
+// Compare returns a negative number when a is more severe than b,
+// a positive number when b is more severe and zero when they are equal.
+func (s Severities) Compare(a, b Severity) int {
+	return s.Rank(a) - s.Rank(b)
+}
+
+// AtLeast reports whether sev is as severe as min or more.
+func (s Severities) AtLeast(sev, min Severity) bool {
+	return s.Compare(sev, min) <= 0
+}
+
+// Highest returns the most severe of the severities of issues,
+// or "" when there are no issues.
+func (s Severities) Highest(issues []Issue) Severity {
+	var highest Severity
+	for _, issue := range issues {
+		if highest == "" || s.Compare(issue.Severity, highest) < 0 {
+			highest = issue.Severity
+		}
+	}
+	return highest
+}
+
+// SARIFLevel returns the SARIF result level of sev, "note" for unknown severities.
+func (s Severities) SARIFLevel(sev Severity) string {
+	if level, ok := s.Level(sev); ok && level.SARIF != "" {
+		return level.SARIF
+	}
+	return SARIFNote
+}
+
+// Sort sorts names from the most to the least severe,
+// unknown severities are sorted alphabetically after the known ones.
+func (s Severities) Sort(names []string) {
+	sort.SliceStable(names, func(i, k int) bool {
+		if c := s.Compare(Severity(names[i]), Severity(names[k])); c != 0 {
+			return c < 0
+		}
+		return names[i] < names[k]
+	})
+}
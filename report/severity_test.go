@@ -0,0 +1,63 @@
+package report
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSeverities(t *testing.T) {
+	s := DefaultSeverities
+
+	if s.Compare(SeverityCritical, SeverityHigh) >= 0 {
+		t.Error("critical should be more severe than high")
+	}
+	if s.Compare("important", SeverityInfo) <= 0 {
+		t.Error("unknown severity should be less severe than info")
+	}
+	if !s.AtLeast(SeverityHigh, SeverityMedium) || s.AtLeast(SeverityLow, SeverityMedium) {
+		t.Error("AtLeast(medium) should include high and exclude low")
+	}
+
+	levels := map[Severity]string{
+		SeverityCritical: SARIFError,
+		SeverityHigh:     SARIFError,
+		SeverityMedium:   SARIFWarning,
+		SeverityLow:      SARIFNote,
+		SeverityInfo:     SARIFNote,
+		"important":      SARIFNote,
+	}
+	for sev, want := range levels {
+		if got := s.SARIFLevel(sev); got != want {
+			t.Errorf("SARIFLevel(%s) = %s, want %s", sev, got, want)
+		}
+	}
+
+	names := []string{"low", "zzz", "critical", "aaa", "medium"}
+	s.Sort(names)
+	if want := []string{"critical", "medium", "low", "aaa", "zzz"}; !slices.Equal(names, want) {
+		t.Errorf("Sort = %v, want %v", names, want)
+	}
+
+	if got := s.Highest([]Issue{{Severity: SeverityLow}, {Severity: SeverityHigh}, {Severity: "other"}}); got != SeverityHigh {
+		t.Errorf("Highest = %s, want high", got)
+	}
+}
+
+func TestReport_CriticalUnits(t *testing.T) {
+	r := NewReport()
+	r.Metadata.Severities = Severities{
+		{Name: "blocker", SARIF: SARIFError},
+		{Name: "nit", SARIF: SARIFNote},
+	}
+
+	r.AddUnit("a", UnitReport{Issues: []Issue{{Severity: "nit"}, {Severity: "blocker"}, {Severity: "blocker"}}})
+	r.AddUnit("b", UnitReport{Issues: []Issue{{Severity: "nit"}}})
+	r.AddIssue("c", Issue{Severity: SeverityCritical})
+
+	if !slices.Equal(r.Summary.CriticalUnits, []string{"a"}) {
+		t.Errorf("CriticalUnits = %v, want [a]", r.Summary.CriticalUnits)
+	}
+	if r.Summary.TotalIssues != 5 || r.Summary.BySeverity["blocker"] != 2 {
+		t.Errorf("summary = %+v", r.Summary)
+	}
+}
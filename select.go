@@ -0,0 +1,122 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/loov/dreamlint/extract"
+	"github.com/loov/dreamlint/report"
+)
+
+// selectedIssue is an issue of a report selected by an issueSelector.
+type selectedIssue struct {
+	unitID   string
+	issue    *report.Issue
+	function report.FunctionInfo
+	// filename is the absolute path of the file containing the issue.
+	filename string
+}
+
+// position returns the location of the issue as written in the report.
+func (t *selectedIssue) position() string {
+	return fmt.Sprintf("%s:%d", t.issue.Position.Filename, t.issue.Position.Line)
+}
+
+// issueSelector selects issues of a report by category, severity and unit.
+type issueSelector struct {
+	categories []string        // empty selects all categories
+	severity   report.Severity // least severe issue, empty selects all
+	units      extract.Patterns
+}
+
+// newIssueSelector returns a selector of issues of rpt, where empty
+// arguments select all issues.
+func newIssueSelector(rpt *report.Report, categories []string, severity string, units []string) (issueSelector, error) {
+	selector := issueSelector{categories: categories, severity: report.Severity(severity)}
+	if severity != "" {
+		if _, ok := rpt.Severities().Level(selector.severity); !ok {
+			return selector, fmt.Errorf("unknown severity %q, want one of %s", severity, strings.Join(rpt.Severities().Names(), ", "))
+		}
+	}
+	var err error
+	selector.units, err = extract.CompilePatterns(units, nil)
+	if err != nil {
+		return selector, fmt.Errorf("unit: %w", err)
+	}
+	return selector, nil
+}
+
+// Select returns the selected issues of rpt, ordered by file and line.
+func (s issueSelector) Select(rpt *report.Report) []*selectedIssue {
+	severities := rpt.Severities()
+
+	var targets []*selectedIssue
+	for unitID, unit := range rpt.Units {
+		if !s.units.Match(unitID) {
+			continue
+		}
+		for i := range unit.Issues {
+			issue := &unit.Issues[i]
+			if len(s.categories) > 0 && !slices.Contains(s.categories, issue.Category) {
+				continue
+			}
+			if s.severity != "" && !severities.AtLeast(issue.Severity, s.severity) {
+				continue
+			}
+			fn, ok := issueFunction(unit, issue)
+			if !ok {
+				continue
+			}
+			targets = append(targets, &selectedIssue{
+				unitID:   unitID,
+				issue:    issue,
+				function: fn,
+				filename: reportFilename(rpt, issue.Position.Filename),
+			})
+		}
+	}
+
+	slices.SortFunc(targets, func(a, b *selectedIssue) int {
+		return cmp.Or(
+			cmp.Compare(a.filename, b.filename),
+			cmp.Compare(a.issue.Position.Line, b.issue.Position.Line),
+			cmp.Compare(a.issue.Category, b.issue.Category),
+		)
+	})
+	return targets
+}
+
+// issueFunction returns the function of unit containing issue.
+func issueFunction(unit report.UnitReport, issue *report.Issue) (report.FunctionInfo, bool) {
+	for _, fn := range unit.Functions {
+		if fn.ID == issue.Function {
+			return fn, true
+		}
+	}
+	// Reports without function IDs have issues only in single function units
+	if issue.Function == "" && len(unit.Functions) == 1 {
+		return unit.Functions[0], true
+	}
+	return report.FunctionInfo{}, false
+}
+
+// reportFilename returns the absolute path of a file name in rpt.
+func reportFilename(rpt *report.Report, filename string) string {
+	if filepath.IsAbs(filename) || rpt.Metadata.Root == "" {
+		return filepath.FromSlash(filename)
+	}
+	return filepath.Join(rpt.Metadata.Root, filepath.FromSlash(filename))
+}
+
+// targetFiles returns the sorted files of targets.
+func targetFiles(targets []*selectedIssue) []string {
+	var files []string
+	for _, target := range targets {
+		files = append(files, target.filename)
+	}
+	slices.Sort(files)
+	return slices.Compact(files)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"go/token"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/loov/dreamlint/extract"
+	"github.com/loov/dreamlint/report"
+)
+
+func TestIssueSelector(t *testing.T) {
+	root := t.TempDir()
+	rpt := report.NewReport()
+	rpt.Metadata.Root = root
+	unit := func(id string, issues ...report.Issue) report.UnitReport {
+		for i := range issues {
+			issues[i].Function = id
+			issues[i].Position.Filename = "pkg/a.go"
+		}
+		return report.UnitReport{
+			Functions: []report.FunctionInfo{{ID: id}},
+			Issues:    issues,
+		}
+	}
+	rpt.Units["example.com/pkg.A"] = unit("example.com/pkg.A",
+		report.Issue{Category: "security", Severity: report.SeverityHigh, Message: "a1", Position: token.Position{Line: 1}},
+		report.Issue{Category: "correctness", Severity: report.SeverityLow, Message: "a2", Position: token.Position{Line: 3}},
+	)
+	rpt.Units["example.com/pkg/sub.B"] = unit("example.com/pkg/sub.B",
+		report.Issue{Category: "security", Severity: report.SeverityCritical, Message: "b1", Position: token.Position{Line: 2}},
+	)
+
+	patterns := func(include ...string) extract.Patterns {
+		ps, err := extract.CompilePatterns(include, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ps
+	}
+	tests := []struct {
+		name     string
+		selector issueSelector
+		want     []string
+	}{
+		// Issues are ordered by file and line
+		{"all", issueSelector{}, []string{"a1", "b1", "a2"}},
+		{"category", issueSelector{categories: []string{"security"}}, []string{"a1", "b1"}},
+		{"severity", issueSelector{severity: report.SeverityHigh}, []string{"a1", "b1"}},
+		{"unit", issueSelector{units: patterns("example.com/pkg.*")}, []string{"a1", "a2"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, target := range test.selector.Select(rpt) {
+				got = append(got, target.issue.Message)
+				if want := filepath.Join(root, "pkg", "a.go"); target.filename != want {
+					t.Errorf("filename = %q, want %q", target.filename, want)
+				}
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
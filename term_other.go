@@ -0,0 +1,15 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package main
+
+import "errors"
+
+// makeRaw is not supported on this platform, keys are read line by line.
+func makeRaw(fd int) (restore func() error, err error) {
+	return nil, errors.ErrUnsupported
+}
+
+// terminalSize is not supported on this platform.
+func terminalSize(fd int) (width, height int, err error) {
+	return 0, 0, errors.ErrUnsupported
+}
@@ -0,0 +1,204 @@
+// Package triage stores decisions about reported issues, so that later runs
+// can suppress false positives and carry forward confirmed issues.
+package triage
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/loov/dreamlint/report"
+)
+
+// Decision is the outcome of triaging an issue
+type Decision string
+
+const (
+	TruePositive  Decision = "true_positive"
+	FalsePositive Decision = "false_positive"
+	WontFix       Decision = "wont_fix"
+)
+
+// Suppresses reports whether issues with the decision are left out of reports.
+func (d Decision) Suppresses() bool {
+	return d == FalsePositive || d == WontFix
+}
+
+// Valid reports whether d is a known decision.
+func (d Decision) Valid() bool {
+	return d == TruePositive || d == FalsePositive || d == WontFix
+}
+
+// Entry is the decision about a single issue
+type Entry struct {
+	Fingerprint string       `json:"fingerprint"`
+	Unit        string       `json:"unit"`
+	Decision    Decision     `json:"decision"`
+	DecidedAt   time.Time    `json:"decided_at"`
+	Issue       report.Issue `json:"issue"`
+}
+
+// Decisions is the content of a triage file
+type Decisions struct {
+	Entries []Entry `json:"decisions"`
+}
+
+// Load reads decisions from path. A missing file has no decisions.
+func Load(path string) (*Decisions, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Decisions{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var d Decisions
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for _, entry := range d.Entries {
+		if !entry.Decision.Valid() {
+			return nil, fmt.Errorf("%s: unknown decision %q for %s", path, entry.Decision, entry.Unit)
+		}
+	}
+	return &d, nil
+}
+
+// Save writes the decisions to path, sorted so that the file diffs well.
+func (d *Decisions) Save(path string) error {
+	slices.SortFunc(d.Entries, func(a, b Entry) int {
+		return cmp.Or(cmp.Compare(a.Unit, b.Unit), cmp.Compare(a.Fingerprint, b.Fingerprint))
+	})
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	// Write to a temporary file first, so an interrupted save keeps the old decisions
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Lookup returns the decision about issue of unit.
+func (d *Decisions) Lookup(unitID string, issue report.Issue) (Entry, bool) {
+	i := d.index(unitID, issue.Fingerprint(unitID))
+	if i < 0 {
+		return Entry{}, false
+	}
+	return d.Entries[i], true
+}
+
+// Set records the decision about issue of unit, replacing an earlier one.
+func (d *Decisions) Set(unitID string, issue report.Issue, decision Decision) {
+	issue.Triage = ""
+	entry := Entry{
+		Fingerprint: issue.Fingerprint(unitID),
+		Unit:        unitID,
+		Decision:    decision,
+		DecidedAt:   time.Now().UTC(),
+		Issue:       issue,
+	}
+	if i := d.index(unitID, entry.Fingerprint); i >= 0 {
+		d.Entries[i] = entry
+		return
+	}
+	d.Entries = append(d.Entries, entry)
+}
+
+// Clear removes the decision about issue of unit.
+func (d *Decisions) Clear(unitID string, issue report.Issue) {
+	if i := d.index(unitID, issue.Fingerprint(unitID)); i >= 0 {
+		d.Entries = slices.Delete(d.Entries, i, i+1)
+	}
+}
+
+func (d *Decisions) index(unitID, fingerprint string) int {
+	return slices.IndexFunc(d.Entries, func(entry Entry) bool {
+		return entry.Unit == unitID && entry.Fingerprint == fingerprint
+	})
+}
+
+// Apply applies the decisions to the issues of a unit and returns the
+// number of suppressed issues. Issues decided to be false positives or not
+// worth fixing are removed and true positives are marked. True positives
+// that were not reported again are carried forward when their code is still
+// in the function, as found in bodies by function ID.
+func (d *Decisions) Apply(unitID string, unit *report.UnitReport, bodies map[string]string) int {
+	suppressed := 0
+	seen := make(map[string]bool)
+	issues := unit.Issues[:0]
+	for _, issue := range unit.Issues {
+		fingerprint := issue.Fingerprint(unitID)
+		seen[fingerprint] = true
+		if i := d.index(unitID, fingerprint); i >= 0 {
+			decision := d.Entries[i].Decision
+			if decision.Suppresses() {
+				suppressed++
+				continue
+			}
+			issue.Triage = string(decision)
+		}
+		issues = append(issues, issue)
+	}
+	unit.Issues = issues
+
+	for _, entry := range d.Entries {
+		if entry.Unit != unitID || entry.Decision != TruePositive || seen[entry.Fingerprint] {
+			continue
+		}
+		if issue, ok := carryForward(entry.Issue, unit.Functions, bodies); ok {
+			issue.Triage = string(TruePositive)
+			unit.Issues = append(unit.Issues, issue)
+		}
+	}
+	return suppressed
+}
+
+// carryForward moves issue to the current line of its code, and reports
+// whether the code is still in the function.
+func carryForward(issue report.Issue, functions []report.FunctionInfo, bodies map[string]string) (report.Issue, bool) {
+	snippet := strings.TrimSpace(issue.Snippet)
+	if snippet == "" {
+		return issue, false
+	}
+	for _, fn := range functions {
+		if fn.ID != issue.Function {
+			continue
+		}
+		for i, line := range strings.Split(bodies[fn.ID], "\n") {
+			if strings.TrimSpace(line) == snippet {
+				issue.Position = fn.Position
+				issue.Position.Line = fn.Position.Line + i
+				issue.EndLine = issue.Position.Line
+				// The fix was made for the earlier version of the file
+				issue.Fix = nil
+				return issue, true
+			}
+		}
+	}
+	return issue, false
+}
@@ -0,0 +1,107 @@
+package triage
+
+import (
+	"go/token"
+	"path/filepath"
+	"testing"
+
+	"github.com/loov/dreamlint/report"
+)
+
+func TestDecisions_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "triage.json")
+
+	d, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load of a missing file: %v", err)
+	}
+	if len(d.Entries) != 0 {
+		t.Fatalf("got %d entries, want none", len(d.Entries))
+	}
+
+	issue := report.Issue{Function: "pkg.A", Category: "security", Snippet: "exec(cmd)", Fix: &report.Fix{Diff: "-"}}
+	d.Set("pkg.A", issue, FalsePositive)
+	d.Set("pkg.A", issue, WontFix)
+	if err := d.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	d, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(d.Entries) != 1 {
+		t.Fatalf("got %d entries, want the decision to be replaced", len(d.Entries))
+	}
+	// The issue is found again after its line moved
+	issue.Position.Line = 42
+	entry, ok := d.Lookup("pkg.A", issue)
+	if !ok || entry.Decision != WontFix {
+		t.Errorf("Lookup = %+v, %v", entry, ok)
+	}
+
+	d.Clear("pkg.A", issue)
+	if _, ok := d.Lookup("pkg.A", issue); ok {
+		t.Error("decision not cleared")
+	}
+}
+
+func TestDecisions_Apply(t *testing.T) {
+	fn := report.FunctionInfo{ID: "pkg.A", Position: token.Position{Filename: "a.go", Line: 10}}
+	issue := func(category, snippet string) report.Issue {
+		return report.Issue{
+			Position: token.Position{Filename: "a.go", Line: 11},
+			Function: "pkg.A",
+			Category: category,
+			Message:  category + " issue",
+			Snippet:  snippet,
+		}
+	}
+	falsePositive := issue("security", "exec(cmd)")
+	confirmed := issue("correctness", "x := y / z")
+	missed := issue("concurrency", "go work()")
+	removed := issue("maintainability", "old()")
+	fresh := issue("correctness", "return nil")
+
+	var d Decisions
+	d.Set("pkg.A", falsePositive, FalsePositive)
+	d.Set("pkg.A", confirmed, TruePositive)
+	d.Set("pkg.A", missed, TruePositive)
+	d.Set("pkg.A", removed, TruePositive)
+	d.Set("pkg.B", fresh, FalsePositive)
+
+	unit := report.UnitReport{
+		Functions: []report.FunctionInfo{fn},
+		Issues:    []report.Issue{falsePositive, confirmed, fresh},
+	}
+	bodies := map[string]string{"pkg.A": "func A() {\n\tx := y / z\n\texec(cmd)\n\n\tgo work()\n}"}
+
+	if suppressed := d.Apply("pkg.A", &unit, bodies); suppressed != 1 {
+		t.Errorf("suppressed = %d, want 1", suppressed)
+	}
+
+	got := make(map[string]report.Issue)
+	for _, issue := range unit.Issues {
+		got[issue.Snippet] = issue
+	}
+	if len(got) != 3 {
+		t.Fatalf("got issues %v, want the confirmed, fresh and carried forward issues", unit.Issues)
+	}
+	if _, ok := got[falsePositive.Snippet]; ok {
+		t.Error("false positive was not suppressed")
+	}
+	if got[confirmed.Snippet].Triage != string(TruePositive) {
+		t.Errorf("confirmed issue triage = %q", got[confirmed.Snippet].Triage)
+	}
+	// The decision about the same issue in another unit does not apply
+	if got[fresh.Snippet].Triage != "" {
+		t.Errorf("fresh issue triage = %q", got[fresh.Snippet].Triage)
+	}
+	carried, ok := got[missed.Snippet]
+	if !ok || carried.Triage != string(TruePositive) || carried.Position.Line != 14 {
+		t.Errorf("carried forward issue = %+v", carried)
+	}
+	if _, ok := got[removed.Snippet]; ok {
+		t.Error("issue of removed code was carried forward")
+	}
+}